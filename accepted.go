@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Date format used for expiry dates
+const expiryDateFormat = "2006-01-02"
+
+// Represents an intentional difference that is accepted until it expires
+type AcceptedDifference struct {
+	Path     string    `yaml:"path"`
+	A        yaml.Node `yaml:"a,omitempty"`        // Expected value of endpoint A
+	B        yaml.Node `yaml:"b,omitempty"`        // Expected value of endpoint B
+	APattern string    `yaml:"aPattern,omitempty"` // Regular expression for the value of endpoint A
+	BPattern string    `yaml:"bPattern,omitempty"` // Regular expression for the value of endpoint B
+	Owner    string    `yaml:"owner"`
+	Expires  string    `yaml:"expires"`
+	Reason   string    `yaml:"reason,omitempty"`
+
+	valueA   interface{}
+	valueB   interface{}
+	patternA *regexp.Regexp
+	patternB *regexp.Regexp
+	expires  time.Time
+}
+
+// Represents the outcome of checking differences against accepted differences
+type acceptanceResult struct {
+	unaccepted []diffInfo            // Differences that are not accepted
+	accepted   []acceptedMatch       // Differences matching a valid entry
+	expired    []acceptedMatch       // Differences matching an expired entry
+	stale      []*AcceptedDifference // Entries that no longer match any difference
+}
+
+// Represents a difference matched by an accepted difference entry
+type acceptedMatch struct {
+	diff  diffInfo
+	entry *AcceptedDifference
+}
+
+// Loads the accepted differences file, decoding it strictly and reporting problems with their positions
+func loadAcceptedDifferences(path string) ([]AcceptedDifference, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, &configError{problems: inAcceptedFile(path, yamlProblems(err, nil))}
+	}
+
+	var entries []AcceptedDifference
+	var problems configProblems
+	findUnknownFields(&doc, reflect.TypeOf(entries), "", &problems)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	// Unknown fields are already reported with a suggestion
+	if err := decoder.Decode(&entries); err != nil && !errors.Is(err, io.EOF) && len(problems) == 0 {
+		problems = append(problems, yamlProblems(err, &doc)...)
+	}
+	if len(problems) > 0 {
+		return nil, &configError{problems: inAcceptedFile(path, problems)}
+	}
+
+	for i := range entries {
+		if err := entries[i].prepare(); err != nil {
+			problem := configProblem{message: fmt.Sprintf("accepted difference %d: %v", i+1, err)}
+			if n := lookupNode(&doc, fmt.Sprintf("[%d]", i)); n != nil {
+				problem.line, problem.column = n.Line, n.Column
+			}
+			return nil, &configError{problems: inAcceptedFile(path, configProblems{problem})}
+		}
+	}
+
+	return entries, nil
+}
+
+// Locates problems in the accepted differences file rather than in the configuration file
+func inAcceptedFile(path string, problems configProblems) configProblems {
+	for i := range problems {
+		problems[i].file = path
+	}
+	return problems
+}
+
+// Validates the entry and converts expected values, patterns and expiry date
+func (e *AcceptedDifference) prepare() error {
+	if e.Path == "" {
+		return errors.New("path is required")
+	}
+	if e.Owner == "" {
+		return errors.New("owner is required for " + e.Path)
+	}
+	if e.Expires == "" {
+		return errors.New("expiry date is required for " + e.Path)
+	}
+
+	expires, err := time.Parse(expiryDateFormat, e.Expires)
+	if err != nil {
+		return fmt.Errorf("invalid expiry date for %s: %w", e.Path, err)
+	}
+	// Entries remain valid through the whole expiry day
+	e.expires = expires.AddDate(0, 0, 1)
+
+	if e.valueA, err = decodeExpectedValue(&e.A); err != nil {
+		return fmt.Errorf("invalid value a for %s: %w", e.Path, err)
+	}
+	if e.valueB, err = decodeExpectedValue(&e.B); err != nil {
+		return fmt.Errorf("invalid value b for %s: %w", e.Path, err)
+	}
+
+	if e.APattern != "" {
+		if e.patternA, err = regexp.Compile(e.APattern); err != nil {
+			return fmt.Errorf("invalid aPattern for %s: %w", e.Path, err)
+		}
+	}
+	if e.BPattern != "" {
+		if e.patternB, err = regexp.Compile(e.BPattern); err != nil {
+			return fmt.Errorf("invalid bPattern for %s: %w", e.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// Decodes an expected value into the representation produced by JSON parsing
+func decodeExpectedValue(node *yaml.Node) (interface{}, error) {
+	// Value not specified
+	if node.Kind == 0 {
+		return nil, nil
+	}
+
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return nil, err
+	}
+	return normalizeJSONValue(value)
+}
+
+// Reports whether the entry has expired at the given time
+func (e *AcceptedDifference) isExpired(now time.Time) bool {
+	return !now.Before(e.expires)
+}
+
+// Reports whether the entry matches a difference
+func (e *AcceptedDifference) matches(d diffInfo) bool {
+	if e.Path != d.path {
+		return false
+	}
+	return matchesExpected(d.valueA, &e.A, e.valueA, e.patternA) &&
+		matchesExpected(d.valueB, &e.B, e.valueB, e.patternB)
+}
+
+// Checks a value against an optional expected value and an optional pattern
+func matchesExpected(actual interface{}, node *yaml.Node, expected interface{}, pattern *regexp.Regexp) bool {
+	if node.Kind != 0 && !reflect.DeepEqual(actual, expected) {
+		return false
+	}
+	if pattern != nil && !pattern.MatchString(valueText(actual)) {
+		return false
+	}
+	return true
+}
+
+// Returns the text matched by value patterns
+func valueText(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return formatValue(v)
+}
+
+// Classifies differences into unaccepted, accepted and expired ones and detects stale entries
+func classifyDifferences(diffs []diffInfo, entries []AcceptedDifference, now time.Time) acceptanceResult {
+	var result acceptanceResult
+	used := make([]bool, len(entries))
+
+	for _, d := range diffs {
+		matched := false
+		for i := range entries {
+			entry := &entries[i]
+			if !entry.matches(d) {
+				continue
+			}
+			used[i] = true
+			matched = true
+			if entry.isExpired(now) {
+				result.expired = append(result.expired, acceptedMatch{d, entry})
+			} else {
+				result.accepted = append(result.accepted, acceptedMatch{d, entry})
+			}
+			break
+		}
+		if !matched {
+			result.unaccepted = append(result.unaccepted, d)
+		}
+	}
+
+	for i := range entries {
+		if !used[i] {
+			result.stale = append(result.stale, &entries[i])
+		}
+	}
+
+	return result
+}
+
+// Reports whether any difference causes the comparison to fail
+func (r acceptanceResult) failed() bool {
+	return len(r.unaccepted) > 0 || len(r.expired) > 0
+}
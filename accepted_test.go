@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifyDifferences(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "accepted.yaml")
+	content := `
+- path: features.newCheckout
+  a: false
+  b: true
+  owner: checkout-team
+  expires: "2026-12-31"
+- path: version
+  aPattern: "^1\\."
+  bPattern: "^2\\."
+  owner: platform-team
+  expires: "2026-01-31"
+- path: limits.max
+  a: 10
+  b: 20
+  owner: platform-team
+  expires: "2026-12-31"
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := loadAcceptedDifferences(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	diffs := []diffInfo{
+		{"features.newCheckout", false, true},
+		{"version", "1.4.0", "2.0.0"},
+		{"limits.min", float64(1), float64(2)},
+	}
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	result := classifyDifferences(diffs, entries, now)
+
+	if len(result.accepted) != 1 || result.accepted[0].diff.path != "features.newCheckout" {
+		t.Errorf("Expected features.newCheckout to be accepted, got %v", result.accepted)
+	}
+	if len(result.expired) != 1 || result.expired[0].diff.path != "version" {
+		t.Errorf("Expected version to be expired, got %v", result.expired)
+	}
+	if len(result.unaccepted) != 1 || result.unaccepted[0].path != "limits.min" {
+		t.Errorf("Expected limits.min to be unaccepted, got %v", result.unaccepted)
+	}
+	if len(result.stale) != 1 || result.stale[0].Path != "limits.max" {
+		t.Errorf("Expected limits.max to be stale, got %v", result.stale)
+	}
+	if !result.failed() {
+		t.Error("Expected result to fail")
+	}
+
+	// Entries remain valid through the expiry day
+	lastDay := time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC)
+	result = classifyDifferences(diffs[:1], entries, lastDay)
+	if len(result.accepted) != 1 || result.failed() {
+		t.Errorf("Expected difference to be accepted on expiry day, got %+v", result)
+	}
+
+	// Values that differ from the expected ones are not accepted
+	result = classifyDifferences([]diffInfo{{"features.newCheckout", true, false}}, entries, now)
+	if len(result.unaccepted) != 1 {
+		t.Errorf("Expected mismatching values to be unaccepted, got %+v", result)
+	}
+}
+
+func TestLoadAcceptedDifferencesValidation(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		want    string // Expected error, after the file name
+	}{
+		{"missing owner", "- path: a\n  expires: \"2026-12-31\"\n", ":1:3: accepted difference 1: owner is required for a"},
+		{"missing expiry", "- path: a\n  owner: team\n", ":1:3: accepted difference 1: expiry date is required for a"},
+		{"invalid expiry", "- path: a\n  owner: team\n  expires: tomorrow\n", ":1:3: accepted difference 1: invalid expiry date for a"},
+		{"invalid pattern", "- path: a\n  owner: team\n  expires: \"2026-12-31\"\n  aPattern: \"[\"\n", ":1:3: accepted difference 1: invalid aPattern for a"},
+		{"unknown field", "- path: a\n  owner: team\n  expires: \"2026-12-31\"\n  reson: typo\n", ":4:3: unknown field reson in [0], did you mean reason?"},
+		{"invalid type", "- path: [a]\n  owner: team\n  expires: \"2026-12-31\"\n", ":1:10: cannot unmarshal !!seq into string"},
+		{"invalid syntax", "- path: a\n owner: team\n", ":1: did not find expected"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "accepted.yaml")
+			if err := os.WriteFile(path, []byte(tc.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadAcceptedDifferences(path)
+			if err == nil || !strings.HasPrefix(err.Error(), path+tc.want) {
+				t.Errorf("Expected error starting with %s%s, got %v", path, tc.want, err)
+			}
+		})
+	}
+}
+
+func TestAcceptedDifferencesOfGroupedDocuments(t *testing.T) {
+	dir := t.TempDir()
+	config := `endpoints:
+  - {name: v1, url: "file://v1.json"}
+  - {name: v2, url: "file://v2.json"}
+settings:
+  jsonPath:
+    database: "$.database"
+  acceptedDifferences: accepted.yaml
+`
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, wantError := range map[string]bool{"database.host": false, "host": true} {
+		accepted := "- path: " + path + "\n  owner: team\n  expires: \"2099-01-01\"\n"
+		if err := os.WriteFile(filepath.Join(dir, "accepted.yaml"), []byte(accepted), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadConfig(filepath.Join(dir, "config.yaml"), "")
+		if wantError && (err == nil || !strings.Contains(err.Error(), "path host does not start with a jsonPath selector name (database)")) {
+			t.Errorf("Expected error for %s, got %v", path, err)
+		}
+		if !wantError && err != nil {
+			t.Errorf("Unexpected error for %s: %v", path, err)
+		}
+	}
+}
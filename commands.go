@@ -0,0 +1,311 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// Version of the binary, set at build time with -ldflags "-X main.version=..."
+var version = ""
+
+// Represents a subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+// Returns the subcommands in the order they are listed in the usage
+func commandList() []command {
+	return []command{
+		{"compare", "Compare two endpoints of a configuration, or two sources", runCompare},
+		{"validate", "Check a configuration file without fetching anything", runValidate},
+		{"explain", "Show what a comparison would do without fetching anything", runExplain},
+		{"snapshot", "Save the responses of all endpoints", runSnapshot},
+		{"verify", "Compare the live responses of all endpoints against their snapshots", runVerify},
+		{"watch", "Rerun the comparison periodically and report changes", runWatch},
+		{"serve", "Expose comparisons as an HTTP API", runServe},
+		{"proxy", "Mirror traffic to two backends and compare their responses", runProxy},
+		{"version", "Print the version", runVersion},
+		{"help", "Show help for a command", runHelp},
+	}
+}
+
+// Returns the subcommand with the given name
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commandList() {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// Prints the list of subcommands
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s <command> [arguments]\n", os.Args[0])
+	fmt.Fprintf(w, "       %s config.yaml [flags] (same as compare)\n\n", os.Args[0])
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commandList() {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun '%s help <command>' for the arguments of a command.\n", os.Args[0])
+}
+
+// Creates the flag set of a subcommand, with a usage built from its argument forms and summary
+func newCommandFlagSet(name string, forms ...string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		w := fs.Output()
+		for i, form := range forms {
+			prefix := "Usage:"
+			if i > 0 {
+				prefix = "      "
+			}
+			fmt.Fprintln(w, strings.TrimRight(fmt.Sprintf("%s %s %s %s", prefix, os.Args[0], name, form), " "))
+		}
+		if cmd, ok := findCommand(name); ok {
+			fmt.Fprintf(w, "\n%s.\n", cmd.summary)
+		}
+
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(w, "\nFlags:")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// Registers the flag selecting a configuration profile
+func profileFlag(fs *flag.FlagSet) *string {
+	return fs.String("profile", "", "configuration profile to apply, e.g. eu")
+}
+
+// Returns the exit code for a flag parsing error, which is 0 when help was requested
+func flagErrorCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	return 2
+}
+
+// Runs the version subcommand and returns the exit code
+func runVersion(args []string) int {
+	fs := newCommandFlagSet("version", "")
+	if err := fs.Parse(args); err != nil {
+		return flagErrorCode(err)
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	fmt.Printf("rest-compare %s\n", currentVersion())
+	return 0
+}
+
+// Returns the version set at build time, or the module version of the build
+func currentVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// Runs the help subcommand and returns the exit code
+func runHelp(args []string) int {
+	if len(args) == 0 {
+		printUsage(os.Stdout)
+		return 0
+	}
+	if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s help [command]\n", os.Args[0])
+		return 2
+	}
+
+	cmd, ok := findCommand(args[0])
+	if !ok || cmd.name == "help" {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", args[0])
+		printUsage(os.Stderr)
+		return 2
+	}
+
+	// Every subcommand prints its usage for -h
+	return cmd.run([]string{"-h"})
+}
+
+// Runs the validate subcommand and returns the exit code
+func runValidate(args []string) int {
+	fs := newCommandFlagSet("validate", "config.yaml")
+	profile := profileFlag(fs)
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return flagErrorCode(err)
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	config, err := LoadConfig(positional[0], *profile)
+	if err != nil {
+		var ce *configError
+		if errors.As(err, &ce) {
+			fmt.Fprintln(os.Stderr, secrets.redact(err.Error()))
+			fmt.Fprintf(os.Stderr, "\n%d problem(s) found\n", len(ce.problems))
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", positional[0], secrets.redact(err.Error()))
+		}
+		return 2
+	}
+
+	fmt.Printf("%s: valid (%d endpoints)\n", positional[0], len(config.Endpoints))
+	return 0
+}
+
+// Runs the explain subcommand and returns the exit code
+func runExplain(args []string) int {
+	fs := newCommandFlagSet("explain", "config.yaml [flags]")
+	profile := profileFlag(fs)
+	var o overrides
+	o.register(fs)
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return flagErrorCode(err)
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	config, err := LoadConfig(positional[0], *profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config file: %v\n", err)
+		return 2
+	}
+	if err := o.apply(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	explainConfig(os.Stdout, config)
+	return 0
+}
+
+// Describes the comparison a configuration runs, without revealing secrets
+func explainConfig(w io.Writer, config *Config) {
+	endpointA, endpointB := config.GetDefaultEndpoints()
+	fmt.Fprintln(w, "Endpoints:")
+	for i, endpoint := range []Endpoint{endpointA, endpointB} {
+		fmt.Fprintf(w, "  %c: %s (%s)\n", 'A'+i, endpoint.Name, endpoint.URL)
+		for _, detail := range describeEndpoint(endpoint) {
+			fmt.Fprintf(w, "     %s\n", detail)
+		}
+	}
+	for _, endpoint := range config.Endpoints[2:] {
+		fmt.Fprintf(w, "  Not compared: %s (%s)\n", endpoint.Name, endpoint.URL)
+	}
+
+	fmt.Fprintln(w, "\nSettings:")
+	fmt.Fprintf(w, "  Mode: %s\n", config.GetMode())
+	switch selectors := config.GetJSONPathSelectors(); {
+	case selectors.grouped():
+		fmt.Fprintln(w, "  JSONPath selectors (differences grouped by name):")
+		for _, selector := range selectors {
+			fmt.Fprintf(w, "    %s: %s\n", selector.Name, selector.Path)
+		}
+	case len(selectors) > 0:
+		fmt.Fprintf(w, "  JSONPath: %s\n", selectors.path())
+	default:
+		fmt.Fprintln(w, "  JSONPath: none (entire response)")
+	}
+	if config.GetJSONPathResults() == jsonPathAll {
+		fmt.Fprintln(w, "  JSONPath results: all nodes, compared by location")
+	}
+	if keys := config.GetIgnoredKeys(); len(keys) > 0 {
+		fmt.Fprintf(w, "  Ignored keys: %s\n", strings.Join(keys, ", "))
+	} else {
+		fmt.Fprintln(w, "  Ignored keys: none")
+	}
+	fmt.Fprintf(w, "  Timeout: %ds\n", config.GetTimeout())
+	if config.GetNoiseSamples() > 1 {
+		fmt.Fprintf(w, "  Noise detection: %d samples per endpoint\n", config.GetNoiseSamples())
+	}
+	if config.Settings.AcceptedDifferences != "" {
+		fmt.Fprintf(w, "  Accepted differences: %d entries (%s)\n", len(config.GetAcceptedDifferences()), config.Settings.AcceptedDifferences)
+	}
+	if config.Settings.Schema != "" {
+		fmt.Fprintf(w, "  Schema: %s\n", config.Settings.Schema)
+	}
+	for i, t := range config.GetTransforms() {
+		fmt.Fprintf(w, "  Transform %d: %s\n", i+1, t.String())
+	}
+	for _, c := range config.GetComparators() {
+		fmt.Fprintf(w, "  Comparator: %s %s\n", c.Type, c.Path)
+	}
+
+	if config.HasReplayRequests() {
+		fmt.Fprintf(w, "\nRequests replayed against both endpoints: %d\n", len(config.GetReplayRequests()))
+		for _, request := range config.GetReplayRequests() {
+			fmt.Fprintf(w, "  %s\n", request)
+		}
+		for _, op := range config.GetSkippedOperations() {
+			fmt.Fprintf(w, "  Not exercised: %s: %s\n", op.operation, op.reason)
+		}
+	}
+}
+
+// Describes how an endpoint is fetched
+func describeEndpoint(endpoint Endpoint) []string {
+	var details []string
+
+	switch {
+	case endpoint.OAuth2 != nil:
+		details = append(details, "Authentication: OAuth2 client credentials ("+endpoint.OAuth2.TokenURL+")")
+	case endpoint.Auth != "":
+		// Only the scheme is shown, the credentials may be secret
+		if scheme, _, ok := strings.Cut(endpoint.Auth, " "); ok {
+			details = append(details, "Authentication: "+scheme+" Authorization header")
+		} else {
+			details = append(details, "Authentication: Authorization header")
+		}
+	}
+	if len(endpoint.Headers) > 0 {
+		var names []string
+		for name := range endpoint.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		details = append(details, "Headers: "+strings.Join(names, ", "))
+	}
+	if p := endpoint.Pagination; p != nil {
+		details = append(details, fmt.Sprintf("Pagination: %s, up to %d pages", p.Type, p.MaxPages))
+	}
+	for i, step := range endpoint.Steps {
+		method := step.Method
+		if method == "" {
+			method = "GET"
+		}
+		details = append(details, fmt.Sprintf("Step %d: %s %s", i+1, method, step.URL))
+	}
+	if len(endpoint.JSONPath) > 0 {
+		details = append(details, "JSONPath: "+endpoint.JSONPath.String())
+	}
+	for _, from := range sortedSources(endpoint.FieldMap) {
+		details = append(details, fmt.Sprintf("Field map: %s -> %s", from, endpoint.FieldMap[from]))
+	}
+
+	return details
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFindCommand(t *testing.T) {
+	for _, name := range []string{"compare", "validate", "explain", "snapshot", "verify", "watch", "serve", "proxy", "version", "help"} {
+		if _, ok := findCommand(name); !ok {
+			t.Errorf("Command %s not found", name)
+		}
+	}
+
+	// Configuration files and sources are compared without a subcommand
+	for _, arg := range []string{"config.yaml", "https://api.example.com/config", "-"} {
+		if _, ok := findCommand(arg); ok {
+			t.Errorf("Argument %s must not be a command", arg)
+		}
+	}
+}
+
+func TestFlagErrorCode(t *testing.T) {
+	fs := newCommandFlagSet("version", "")
+	fs.SetOutput(&bytes.Buffer{})
+
+	if code := flagErrorCode(fs.Parse([]string{"-h"})); code != 0 {
+		t.Errorf("Expected exit code 0 for help, got %d", code)
+	}
+	if code := flagErrorCode(fs.Parse([]string{"--unknown"})); code != 2 {
+		t.Errorf("Expected exit code 2 for unknown flag, got %d", code)
+	}
+}
+
+func TestExplainConfig(t *testing.T) {
+	config := &Config{
+		Endpoints: []Endpoint{
+			{Name: "Production", URL: "https://api.example.com/config", Auth: "Bearer s3cr3t-token"},
+			{Name: "Staging", URL: "https://staging.example.com/config", Auth: "s3cr3t-key",
+				Pagination: &Pagination{Type: paginationLink, MaxPages: 10}},
+			{Name: "Development", URL: "file://dev.json"},
+		},
+		Settings: Settings{Timeout: 30, IgnoredKeys: []string{"id", "timestamp"}, Mode: modeValues},
+	}
+
+	var out bytes.Buffer
+	explainConfig(&out, config)
+	text := out.String()
+
+	for _, want := range []string{
+		"A: Production (https://api.example.com/config)",
+		"Authentication: Bearer Authorization header",
+		"Pagination: link, up to 10 pages",
+		"Not compared: Development (file://dev.json)",
+		"JSONPath: none (entire response)",
+		"Ignored keys: id, timestamp",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Explanation does not contain %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "s3cr3t") {
+		t.Errorf("Explanation reveals credentials:\n%s", text)
+	}
+}
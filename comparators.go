@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/theory/jsonpath"
+)
+
+// Comparator types
+const (
+	comparatorTimestamp = "timestamp" // Timestamps denoting the same instant are equal, whatever their time zone
+	comparatorURL       = "url"       // URLs are equal regardless of query parameter order, host case and default ports
+	comparatorBoolean   = "boolean"   // Booleans are equal to the strings "true" and "false"
+	comparatorNumber    = "number"    // Numbers are equal to strings holding the same number
+)
+
+// Represents a comparison of the values selected by a JSONPath by their meaning instead of their representation
+type Comparator struct {
+	Path string `yaml:"path"`
+	Type string `yaml:"type"`
+}
+
+// Checks the JSONPath and the type
+func (c *Comparator) validate() error {
+	if c.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if _, err := jsonpath.Parse(c.Path); err != nil {
+		return fmt.Errorf("invalid path: %v", err)
+	}
+
+	switch c.Type {
+	case comparatorTimestamp, comparatorURL, comparatorBoolean, comparatorNumber:
+		return nil
+	case "":
+		return fmt.Errorf("type is required")
+	default:
+		return fmt.Errorf("unknown type: %s (expected %s, %s, %s or %s)", c.Type,
+			comparatorTimestamp, comparatorURL, comparatorBoolean, comparatorNumber)
+	}
+}
+
+// Returns the comparator type of every path selected in either document, in the format of difference paths.
+// When comparators select the same path, the last one applies.
+func locateComparators(comparators []Comparator, a, b interface{}) map[string]string {
+	if len(comparators) == 0 {
+		return nil
+	}
+
+	locations := make(map[string]string)
+	for _, c := range comparators {
+		p, err := jsonpath.Parse(c.Path)
+		if err != nil {
+			continue
+		}
+		for _, data := range []interface{}{a, b} {
+			for _, node := range p.SelectLocated(data) {
+				locations[formatDocumentPath(locatedSegments(node.Path))] = c.Type
+			}
+		}
+	}
+	return locations
+}
+
+// Formats path segments as a difference path, e.g. items[0].name
+func formatDocumentPath(segments []pathSegment) string {
+	path := ""
+	for _, segment := range segments {
+		if segment.key != "" {
+			path = createPath(path, segment.key)
+		} else {
+			path = fmt.Sprintf("%s[%d]", path, segment.index)
+		}
+	}
+	return path
+}
+
+// Returns the difference path of a go-cmp path
+func cmpDocumentPath(p cmp.Path) string {
+	path := ""
+	for _, step := range p {
+		switch s := step.(type) {
+		case cmp.MapIndex:
+			path = createPath(path, fmt.Sprintf("%v", s.Key()))
+		case cmp.SliceIndex:
+			path = fmt.Sprintf("%s[%d]", path, s.Key())
+		}
+	}
+	return path
+}
+
+// Returns go-cmp options comparing the located scalar values with their comparators
+func comparatorOptions(opts *compareOptions) []cmp.Option {
+	if opts == nil || len(opts.comparatorAt) == 0 {
+		return nil
+	}
+
+	var options []cmp.Option
+	for _, kind := range []string{comparatorTimestamp, comparatorURL, comparatorBoolean, comparatorNumber} {
+		options = append(options, cmp.FilterPath(func(p cmp.Path) bool {
+			vx, vy := p.Last().Values()
+			if !vx.IsValid() || !vy.IsValid() || !isScalar(vx) || !isScalar(vy) {
+				return false
+			}
+			if mapIdx, ok := p.Last().(cmp.MapIndex); ok && isIgnoredKey(fmt.Sprintf("%v", mapIdx.Key()), opts.ignored()) {
+				return false
+			}
+			return opts.comparatorFor(cmpDocumentPath(p)) == kind
+		}, cmp.Comparer(func(x, y interface{}) bool {
+			return equivalentValues(kind, x, y)
+		})))
+	}
+	return options
+}
+
+// Reports whether a value decoded from JSON is a string, number or boolean
+func isScalar(v reflect.Value) bool {
+	for v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String, reflect.Float64, reflect.Bool:
+		return true
+	}
+	return false
+}
+
+// Reports whether two values are equal according to a comparator type,
+// falling back to strict equality when a value cannot be interpreted
+func equivalentValues(kind string, a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+
+	switch kind {
+	case comparatorTimestamp:
+		timeA, okA := parseTimestamp(a)
+		timeB, okB := parseTimestamp(b)
+		return okA && okB && timeA.Equal(timeB)
+	case comparatorURL:
+		urlA, okA := normalizeURL(a)
+		urlB, okB := normalizeURL(b)
+		return okA && okB && urlA == urlB
+	case comparatorBoolean:
+		boolA, okA := looseBool(a)
+		boolB, okB := looseBool(b)
+		return okA && okB && boolA == boolB
+	case comparatorNumber:
+		numberA, okA := looseNumber(a)
+		numberB, okB := looseNumber(b)
+		return okA && okB && numberA == numberB
+	}
+	return false
+}
+
+// Layouts accepted for timestamps, with a time zone
+var timestampLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05Z07:00", time.RFC1123Z, time.RFC1123}
+
+// Parses a timestamp string
+func parseTimestamp(v interface{}) (time.Time, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Returns a URL in a canonical form: lowercase scheme and host, no default port,
+// a root path of "/" and query parameters sorted by name
+func normalizeURL(v interface{}) (string, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return "", false
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", false
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		u.Host = u.Hostname()
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	// Encode sorts the parameters by name, keeping the order of repeated parameters
+	u.RawQuery = u.Query().Encode()
+	return u.String(), true
+}
+
+// Interprets a boolean or a "true"/"false" string
+func looseBool(v interface{}) (bool, bool) {
+	switch value := v.(type) {
+	case bool:
+		return value, true
+	case string:
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// Interprets a number or a string holding a number
+func looseNumber(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case float64:
+		return value, true
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return number, err == nil
+	}
+	return 0, false
+}
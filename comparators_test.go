@@ -0,0 +1,109 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEquivalentValues(t *testing.T) {
+	testCases := []struct {
+		name string
+		kind string
+		a, b interface{}
+		want bool
+	}{
+		{"same instant", comparatorTimestamp, "2026-01-01T00:00:00Z", "2026-01-01T09:00:00+09:00", true},
+		{"fractional seconds", comparatorTimestamp, "2026-01-01T00:00:00.000Z", "2026-01-01T00:00:00Z", true},
+		{"different instant", comparatorTimestamp, "2026-01-01T00:00:00Z", "2026-01-01T00:00:00+09:00", false},
+		{"not a timestamp", comparatorTimestamp, "yesterday", "2026-01-01T00:00:00Z", false},
+		{"reordered query", comparatorURL, "https://example.com/a?x=1&y=2", "https://example.com/a?y=2&x=1", true},
+		{"host case and default port", comparatorURL, "HTTPS://Example.com:443", "https://example.com/", true},
+		{"different path", comparatorURL, "https://example.com/a", "https://example.com/b", false},
+		{"repeated parameter order", comparatorURL, "https://example.com/?x=1&x=2", "https://example.com/?x=2&x=1", false},
+		{"boolean string", comparatorBoolean, "true", true, true},
+		{"boolean string case", comparatorBoolean, "FALSE", false, true},
+		{"different boolean", comparatorBoolean, "true", false, false},
+		{"number string", comparatorNumber, "30", float64(30), true},
+		{"number formats", comparatorNumber, "30.0", "3e1", true},
+		{"different number", comparatorNumber, "30", float64(31), false},
+		{"not a number", comparatorNumber, "thirty", float64(30), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := equivalentValues(tc.kind, tc.a, tc.b); got != tc.want {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateComparator(t *testing.T) {
+	testCases := []struct {
+		name       string
+		comparator Comparator
+		wantError  bool
+	}{
+		{name: "timestamp", comparator: Comparator{Path: "$..createdAt", Type: comparatorTimestamp}},
+		{name: "missing path", comparator: Comparator{Type: comparatorURL}, wantError: true},
+		{name: "invalid path", comparator: Comparator{Path: "$[bad", Type: comparatorURL}, wantError: true},
+		{name: "missing type", comparator: Comparator{Path: "$.a"}, wantError: true},
+		{name: "unknown type", comparator: Comparator{Path: "$.a", Type: "date"}, wantError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.comparator.validate()
+			if tc.wantError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tc.wantError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCompareWithComparators(t *testing.T) {
+	a, _ := parseJSON([]byte(`{"createdAt": "2026-01-01T00:00:00Z", "links": ["https://x/?a=1&b=2"], "enabled": "true",
+		"items": [{"timeout": "30", "name": "a"}], "count": "1", "secret": "2026-01-01T00:00:00Z"}`))
+	b, _ := parseJSON([]byte(`{"createdAt": "2026-01-01T09:00:00+09:00", "links": ["https://x/?b=2&a=1"], "enabled": true,
+		"items": [{"timeout": 30, "name": "a"}], "count": 1, "secret": "changed"}`))
+
+	opts := &compareOptions{
+		ignoreKeys: []string{"secret"},
+		comparators: []Comparator{
+			{Path: "$.createdAt", Type: comparatorTimestamp},
+			{Path: "$.links[*]", Type: comparatorURL},
+			{Path: "$.enabled", Type: comparatorBoolean},
+			{Path: "$..timeout", Type: comparatorNumber},
+			{Path: "$.secret", Type: comparatorTimestamp},
+		},
+	}
+
+	// Only the value without a comparator differs
+	diffs := diffJSON(a, b, opts)
+	want := []diffInfo{{"count", "1", float64(1)}}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("Expected %v, got %v", want, diffs)
+	}
+
+	// The equality check uses the comparators as well
+	opts.comparators = append(opts.comparators, Comparator{Path: "$.count", Type: comparatorNumber})
+	plainA, plainB := copyJSONValue(a).(map[string]interface{}), copyJSONValue(b).(map[string]interface{})
+	delete(plainA, "secret")
+	delete(plainB, "secret")
+	if d := cmp.Diff(plainA, plainB, comparatorOptions(opts.locate(plainA, plainB))...); d != "" {
+		t.Errorf("Unexpected go-cmp difference:\n%s", d)
+	}
+	if equal, diffs := compareJSONWithOptions(a, b, opts); !equal {
+		t.Errorf("Expected equal documents, got %v", diffs)
+	}
+
+	// Without comparators the representation matters
+	if diffs := diffJSON(a, b, &compareOptions{ignoreKeys: []string{"secret"}}); len(diffs) != 5 {
+		t.Errorf("Expected 5 differences, got %v", diffs)
+	}
+}
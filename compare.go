@@ -7,10 +7,91 @@ import (
 	"github.com/google/go-cmp/cmp"
 )
 
+// Comparison modes
+const (
+	modeValues    = "values"    // Compare keys, types and values
+	modeStructure = "structure" // Compare keys and JSON types only
+)
+
+// Controls how two JSON objects are compared
+type compareOptions struct {
+	ignoreKeys  []string
+	mode        string
+	comparators []Comparator
+
+	comparatorAt map[string]string // Comparator type by difference path, located in the documents being compared
+}
+
+// Creates comparison options from the configuration settings
+func newCompareOptions(config *Config) *compareOptions {
+	return &compareOptions{
+		ignoreKeys:  config.GetIgnoredKeys(),
+		mode:        config.GetMode(),
+		comparators: config.GetComparators(),
+	}
+}
+
+// Reports whether only the structure of the documents is compared
+func (o *compareOptions) structureOnly() bool {
+	return o != nil && o.mode == modeStructure
+}
+
+// Returns the list of keys to ignore
+func (o *compareOptions) ignored() []string {
+	if o == nil {
+		return nil
+	}
+	return o.ignoreKeys
+}
+
+// Returns a copy of the options with the comparators located in both documents
+func (o *compareOptions) locate(a, b interface{}) *compareOptions {
+	if o == nil || len(o.comparators) == 0 {
+		return o
+	}
+	located := *o
+	located.comparatorAt = locateComparators(o.comparators, a, b)
+	return &located
+}
+
+// Returns the comparator type applying to a difference path, or an empty string
+func (o *compareOptions) comparatorFor(path string) string {
+	if o == nil {
+		return ""
+	}
+	return o.comparatorAt[path]
+}
+
 // Compares two JSON objects and returns whether they are equal and difference information
 func compareJSON(a, b interface{}, ignoreKeys []string) (bool, []string) {
+	return compareJSONWithOptions(a, b, &compareOptions{ignoreKeys: ignoreKeys})
+}
+
+// Compares two JSON objects using the given options
+func compareJSONWithOptions(a, b interface{}, opts *compareOptions) (bool, []string) {
+	diffs := diffJSON(a, b, opts)
+
+	// If no difference, they are equal
+	if len(diffs) == 0 {
+		return true, nil
+	}
+
+	// Format difference information
+	return false, formatDifferences(diffs)
+}
+
+// Compares two JSON objects and returns the differences found
+func diffJSON(a, b interface{}, opts *compareOptions) []diffInfo {
+	// Structure comparison relies entirely on the path walk
+	if opts.structureOnly() {
+		return findDiffPaths(a, b, "", opts)
+	}
+
+	opts = opts.locate(a, b)
+	ignoreKeys := opts.ignored()
+
 	// Set up go-cmp options
-	opts := []cmp.Option{
+	cmpOpts := []cmp.Option{
 		cmp.FilterPath(func(p cmp.Path) bool {
 			if len(p) == 0 {
 				return false
@@ -30,35 +111,32 @@ func compareJSON(a, b interface{}, ignoreKeys []string) (bool, []string) {
 			return false
 		}, cmp.Ignore()),
 	}
+	cmpOpts = append(cmpOpts, comparatorOptions(opts)...)
 
 	// Execute comparison
-	diff := cmp.Diff(a, b, opts...)
-
-	// If no difference, they are equal
-	if diff == "" {
-		return true, nil
+	if cmp.Diff(a, b, cmpOpts...) == "" {
+		return nil
 	}
 
-	// Format difference information
-	diffs := formatDifferences(a, b, ignoreKeys)
-	return false, diffs
+	// Detect differences through deep comparison
+	return findDiffPaths(a, b, "", opts)
 }
 
 // Formats difference information in a readable format
-func formatDifferences(a, b interface{}, ignoreKeys []string) []string {
+func formatDifferences(diffs []diffInfo) []string {
 	var result []string
-
-	// Detect differences through deep comparison
-	diffPaths := findDiffPaths(a, b, "", ignoreKeys)
-
-	for _, diffInfo := range diffPaths {
-		result = append(result, fmt.Sprintf("- Path: %s\n  A: %v\n  B: %v",
-			diffInfo.path, formatValue(diffInfo.valueA), formatValue(diffInfo.valueB)))
+	for _, d := range diffs {
+		result = append(result, formatDiff(d))
 	}
-
 	return result
 }
 
+// Formats a single difference, redacting secret values
+func formatDiff(d diffInfo) string {
+	return secrets.redact(fmt.Sprintf("- Path: %s\n  A: %v\n  B: %v",
+		d.path, formatValue(d.valueA), formatValue(d.valueB)))
+}
+
 // Represents difference information
 type diffInfo struct {
 	path   string
@@ -67,7 +145,19 @@ type diffInfo struct {
 }
 
 // Recursively finds difference paths between two objects
-func findDiffPaths(a, b interface{}, currentPath string, ignoreKeys []string) []diffInfo {
+func findDiffPaths(a, b interface{}, currentPath string, opts *compareOptions) []diffInfo {
+	if opts.structureOnly() {
+		return findStructureDiffPaths(a, b, currentPath, opts)
+	}
+
+	// Compare scalar values by their meaning when a comparator applies
+	if kind := opts.comparatorFor(currentPath); kind != "" && isScalar(reflect.ValueOf(a)) && isScalar(reflect.ValueOf(b)) {
+		if equivalentValues(kind, a, b) {
+			return nil
+		}
+		return []diffInfo{{currentPath, a, b}}
+	}
+
 	// Check for type differences
 	typeA, typeB := reflect.TypeOf(a), reflect.TypeOf(b)
 	if typeA != typeB {
@@ -83,14 +173,14 @@ func findDiffPaths(a, b interface{}, currentPath string, ignoreKeys []string) []
 	mapA, okA := a.(map[string]interface{})
 	mapB, okB := b.(map[string]interface{})
 	if okA && okB {
-		return findMapDifferences(mapA, mapB, currentPath, ignoreKeys)
+		return findMapDifferences(mapA, mapB, currentPath, opts)
 	}
 
 	// Handle slices
 	sliceA, okA := a.([]interface{})
 	sliceB, okB := b.([]interface{})
 	if okA && okB {
-		return findSliceDifferences(sliceA, sliceB, currentPath, ignoreKeys)
+		return findSliceDifferences(sliceA, sliceB, currentPath, opts)
 	}
 
 	// Handle other cases (primitive values, etc.)
@@ -98,7 +188,7 @@ func findDiffPaths(a, b interface{}, currentPath string, ignoreKeys []string) []
 }
 
 // Finds differences between two maps
-func findMapDifferences(mapA, mapB map[string]interface{}, currentPath string, ignoreKeys []string) []diffInfo {
+func findMapDifferences(mapA, mapB map[string]interface{}, currentPath string, opts *compareOptions) []diffInfo {
 	var results []diffInfo
 
 	// Collect all keys from both maps
@@ -113,7 +203,7 @@ func findMapDifferences(mapA, mapB map[string]interface{}, currentPath string, i
 	// Check each key for differences
 	for k := range allKeys {
 		// Skip keys in ignore list
-		if isIgnoredKey(k, ignoreKeys) {
+		if isIgnoredKey(k, opts.ignored()) {
 			continue
 		}
 
@@ -125,23 +215,23 @@ func findMapDifferences(mapA, mapB map[string]interface{}, currentPath string, i
 
 		// Handle keys that exist in only one map
 		if !existsA {
-			results = append(results, diffInfo{newPath, "[missing]", valueB})
+			results = append(results, diffInfo{newPath, "[missing]", describeValue(valueB, opts)})
 			continue
 		}
 		if !existsB {
-			results = append(results, diffInfo{newPath, valueA, "[missing]"})
+			results = append(results, diffInfo{newPath, describeValue(valueA, opts), "[missing]"})
 			continue
 		}
 
 		// Recursively compare values that exist in both maps
-		results = append(results, findDiffPaths(valueA, valueB, newPath, ignoreKeys)...)
+		results = append(results, findDiffPaths(valueA, valueB, newPath, opts)...)
 	}
 
 	return results
 }
 
 // Finds differences between two slices
-func findSliceDifferences(sliceA, sliceB []interface{}, currentPath string, ignoreKeys []string) []diffInfo {
+func findSliceDifferences(sliceA, sliceB []interface{}, currentPath string, opts *compareOptions) []diffInfo {
 	// Handle different lengths
 	if len(sliceA) != len(sliceB) {
 		return []diffInfo{{currentPath,
@@ -154,7 +244,7 @@ func findSliceDifferences(sliceA, sliceB []interface{}, currentPath string, igno
 	// Compare each element
 	for i := 0; i < len(sliceA); i++ {
 		newPath := fmt.Sprintf("%s[%d]", currentPath, i)
-		results = append(results, findDiffPaths(sliceA[i], sliceB[i], newPath, ignoreKeys)...)
+		results = append(results, findDiffPaths(sliceA[i], sliceB[i], newPath, opts)...)
 	}
 
 	return results
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -449,7 +450,7 @@ func TestMapDifferences(t *testing.T) {
 		}
 
 		// Test with ignored key
-		diffs = findMapDifferences(mapA, mapB, "root", []string{"diff"})
+		diffs = findMapDifferences(mapA, mapB, "root", &compareOptions{ignoreKeys: []string{"diff"}})
 
 		// Should find 2 differences: onlyA and onlyB (diff is ignored)
 		if len(diffs) != 2 {
@@ -624,6 +625,45 @@ func TestExtractPath(t *testing.T) {
 	}
 }
 
+func TestExtractNodes(t *testing.T) {
+	dataA, _ := parseJSON([]byte(`{"items": [{"id": 1, "enabled": true, "timeout": 5}, {"id": 2, "enabled": false}, {"id": 3, "enabled": true, "timeout": 10}]}`))
+	dataB, _ := parseJSON([]byte(`{"items": [{"id": 1, "enabled": true, "timeout": 5}, {"id": 2, "enabled": true, "timeout": 7}, {"id": 3, "enabled": true, "timeout": 20}]}`))
+
+	nodesA, err := extractNodes(dataA, "$..timeout")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]interface{}{"$['items'][0]['timeout']": float64(5), "$['items'][2]['timeout']": float64(10)}
+	if !reflect.DeepEqual(nodesA, want) {
+		t.Errorf("Expected %v, got %v", want, nodesA)
+	}
+
+	// Nodes selected on one side only and changed values are reported per location
+	nodesB, err := extractNodes(dataB, "$..timeout")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got := make(map[string]string)
+	for _, d := range diffJSON(nodesA, nodesB, nil) {
+		got[d.path] = formatValue(d.valueA) + " -> " + formatValue(d.valueB)
+	}
+	wantDiffs := map[string]string{
+		"$['items'][1]['timeout']": "[missing] -> 7",
+		"$['items'][2]['timeout']": "10 -> 20",
+	}
+	if !reflect.DeepEqual(got, wantDiffs) {
+		t.Errorf("Expected %v, got %v", wantDiffs, got)
+	}
+
+	// No selected node is not an error
+	if nodes, err := extractNodes(dataA, "$.missing[*]"); err != nil || len(nodes) != 0 {
+		t.Errorf("Expected no nodes, got %v (err: %v)", nodes, err)
+	}
+	if _, err := extractNodes(dataA, "$[invalid"); err == nil {
+		t.Error("Expected error for invalid JSONPath")
+	}
+}
+
 // Helper function to compare maps
 func compareMaps(a, b map[string]interface{}) bool {
 	if len(a) != len(b) {
@@ -636,3 +676,138 @@ func compareMaps(a, b map[string]interface{}) bool {
 	}
 	return true
 }
+
+func TestCompareStructure(t *testing.T) {
+	testCases := []struct {
+		name          string
+		a             interface{}
+		b             interface{}
+		ignoreKeys    []string
+		wantEqual     bool
+		expectedPaths []string
+	}{
+		{
+			name: "different values with same types",
+			a: map[string]interface{}{
+				"name":  "prod",
+				"count": float64(10),
+			},
+			b: map[string]interface{}{
+				"name":  "staging",
+				"count": float64(3),
+			},
+			wantEqual: true,
+		},
+		{
+			name: "type change",
+			a: map[string]interface{}{
+				"count": float64(10),
+			},
+			b: map[string]interface{}{
+				"count": "10",
+			},
+			wantEqual:     false,
+			expectedPaths: []string{"count"},
+		},
+		{
+			name: "missing and extra fields",
+			a: map[string]interface{}{
+				"name":  "prod",
+				"extra": true,
+			},
+			b: map[string]interface{}{
+				"name":  "staging",
+				"other": float64(1),
+			},
+			wantEqual:     false,
+			expectedPaths: []string{"extra", "other"},
+		},
+		{
+			name: "ignored missing field",
+			a: map[string]interface{}{
+				"name":  "prod",
+				"extra": true,
+			},
+			b: map[string]interface{}{
+				"name": "staging",
+			},
+			ignoreKeys: []string{"extra"},
+			wantEqual:  true,
+		},
+		{
+			name: "arrays of different lengths with same element shape",
+			a: map[string]interface{}{
+				"items": []interface{}{
+					map[string]interface{}{"id": float64(1)},
+					map[string]interface{}{"id": float64(2)},
+				},
+			},
+			b: map[string]interface{}{
+				"items": []interface{}{
+					map[string]interface{}{"id": float64(3)},
+				},
+			},
+			wantEqual: true,
+		},
+		{
+			name: "array elements compared as union of shapes",
+			a: map[string]interface{}{
+				"items": []interface{}{
+					map[string]interface{}{"id": float64(1)},
+					map[string]interface{}{"label": "x"},
+				},
+			},
+			b: map[string]interface{}{
+				"items": []interface{}{
+					map[string]interface{}{"id": float64(3)},
+				},
+			},
+			wantEqual:     false,
+			expectedPaths: []string{"items[*].label"},
+		},
+		{
+			name: "empty array matches any element shape",
+			a: map[string]interface{}{
+				"items": []interface{}{},
+			},
+			b: map[string]interface{}{
+				"items": []interface{}{"a", "b"},
+			},
+			wantEqual: true,
+		},
+		{
+			name: "mixed element types",
+			a: map[string]interface{}{
+				"items": []interface{}{"a", float64(1)},
+			},
+			b: map[string]interface{}{
+				"items": []interface{}{"b"},
+			},
+			wantEqual:     false,
+			expectedPaths: []string{"number|string"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := &compareOptions{ignoreKeys: tc.ignoreKeys, mode: modeStructure}
+			equal, diffs := compareJSONWithOptions(tc.a, tc.b, opts)
+			if equal != tc.wantEqual {
+				t.Errorf("compareJSONWithOptions() got = %v, want %v (diffs: %v)", equal, tc.wantEqual, diffs)
+			}
+
+			for _, expectedPath := range tc.expectedPaths {
+				found := false
+				for _, diff := range diffs {
+					if strings.Contains(diff, expectedPath) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					t.Errorf("Expected to find difference containing '%s', but not found in: %v", expectedPath, diffs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Configuration keys merged with special semantics
+const (
+	endpointsKey   = "endpoints"
+	ignoredKeysKey = "settings.ignoredKeys"
+)
+
+// Loads a configuration file with its includes and selected profile into one document.
+// Nodes are mapped to the file they come from, so that problems can be located.
+// The document is nil if the file cannot be read or parsed.
+func composeConfig(path, profile string) (*yaml.Node, map[*yaml.Node]string, configProblems) {
+	files := make(map[*yaml.Node]string)
+	root, problems := loadConfigDocument(path, nil, files)
+	if root == nil || profile == "" {
+		return root, files, problems
+	}
+
+	// Overlay the selected profile
+	profiles := mappingValue(root, "profiles")
+	overlay := mappingValue(profiles, profile)
+	if overlay == nil {
+		problem := configProblem{path: "profiles", message: "unknown profile: " + profile}
+		if names := mappingKeys(profiles); len(names) > 0 {
+			problem.message += " (available: " + strings.Join(names, ", ") + ")"
+		}
+		located := configProblems{problem}
+		locateProblems(root, files, located)
+		return nil, files, append(problems, located...)
+	}
+	return mergeNodes(root, overlay, "", files), files, problems
+}
+
+// Loads a configuration document, merging the fragments it includes before its own values
+func loadConfigDocument(path string, chain []string, files map[*yaml.Node]string) (*yaml.Node, configProblems) {
+	inFile := func(problems configProblems) configProblems {
+		for i := range problems {
+			if problems[i].file == "" {
+				problems[i].file = path
+			}
+		}
+		return problems
+	}
+
+	// Files being included, to detect cycles
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, inFile(configProblems{{message: err.Error()}})
+	}
+	chain = append(chain, absPath)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, inFile(configProblems{{message: err.Error()}})
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, inFile(yamlProblems(err, nil))
+	}
+	root := &yaml.Node{Kind: yaml.MappingNode, Line: 1, Column: 1}
+	if len(doc.Content) > 0 {
+		root = doc.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, inFile(configProblems{{line: root.Line, column: root.Column, message: "configuration must be a mapping"}})
+	}
+	registerNodes(root, path, files)
+
+	// Check the document on its own, so that problems point to the right file
+	var config Config
+	problems := inFile(decodeConfig(root, &config))
+
+	// Fragments are merged in order, then overridden by the including document
+	merged := &yaml.Node{Kind: yaml.MappingNode, Line: 1, Column: 1}
+	for i, include := range config.Include {
+		includePath := resolvePath(filepath.Dir(path), include)
+
+		// Point to the include entry when the fragment cannot be included
+		n := lookupNode(root, fmt.Sprintf("include[%d]", i))
+		if _, err := os.Stat(includePath); err != nil {
+			problems = append(problems, configProblem{file: path, line: n.Line, column: n.Column, message: "include: " + err.Error()})
+			continue
+		}
+		if cycle := includeCycle(chain, includePath); cycle != "" {
+			problems = append(problems, configProblem{file: path, line: n.Line, column: n.Column, message: "include cycle: " + cycle})
+			continue
+		}
+
+		fragment, found := loadConfigDocument(includePath, chain, files)
+		problems = append(problems, found...)
+		if fragment != nil {
+			merged = mergeNodes(merged, fragment, "", files)
+		}
+	}
+
+	return mergeNodes(merged, root, "", files), problems
+}
+
+// Returns the include chain leading back to a file, or an empty string if including it is not a cycle
+func includeCycle(chain []string, path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	for i, included := range chain {
+		if included == absPath {
+			var names []string
+			for _, p := range append(chain[i:], absPath) {
+				names = append(names, filepath.Base(p))
+			}
+			return strings.Join(names, " -> ")
+		}
+	}
+	return ""
+}
+
+// Maps every node of a document to its file
+func registerNodes(n *yaml.Node, path string, files map[*yaml.Node]string) {
+	files[n] = path
+	for _, child := range n.Content {
+		registerNodes(child, path, files)
+	}
+}
+
+// Merges an overlay into a base node:
+// mappings are merged key by key, endpoints are merged by name,
+// ignored keys are the union of both lists, and other values are replaced
+func mergeNodes(base, overlay *yaml.Node, path string, files map[*yaml.Node]string) *yaml.Node {
+	if base != nil && base.Kind == yaml.AliasNode {
+		base = base.Alias
+	}
+	if overlay.Kind == yaml.AliasNode {
+		overlay = overlay.Alias
+	}
+	if base == nil || base.Kind != overlay.Kind {
+		return overlay
+	}
+
+	switch {
+	case overlay.Kind == yaml.MappingNode:
+		merged := newMergedNode(base, overlay, files)
+		for i := 0; i+1 < len(overlay.Content); i += 2 {
+			key, value := overlay.Content[i], overlay.Content[i+1]
+			index := mappingIndex(merged, key.Value)
+			if index < 0 {
+				merged.Content = append(merged.Content, key, value)
+				continue
+			}
+			merged.Content[index+1] = mergeNodes(merged.Content[index+1], value, joinConfigPath(path, key.Value), files)
+		}
+		return merged
+
+	case overlay.Kind == yaml.SequenceNode && path == endpointsKey:
+		merged := newMergedNode(base, overlay, files)
+		for _, endpoint := range overlay.Content {
+			name := mappingValue(endpoint, "name")
+			index := -1
+			for i, existing := range merged.Content {
+				if existingName := mappingValue(existing, "name"); name != nil && existingName != nil && existingName.Value == name.Value {
+					index = i
+					break
+				}
+			}
+			if index < 0 {
+				merged.Content = append(merged.Content, endpoint)
+				continue
+			}
+			merged.Content[index] = mergeNodes(merged.Content[index], endpoint, path+"[]", files)
+		}
+		return merged
+
+	case overlay.Kind == yaml.SequenceNode && path == ignoredKeysKey:
+		merged := newMergedNode(base, overlay, files)
+		for _, key := range overlay.Content {
+			duplicate := false
+			for _, existing := range merged.Content {
+				if existing.Kind == yaml.ScalarNode && existing.Value == key.Value {
+					duplicate = true
+					break
+				}
+			}
+			if !duplicate {
+				merged.Content = append(merged.Content, key)
+			}
+		}
+		return merged
+
+	default:
+		return overlay
+	}
+}
+
+// Creates a node holding the content of the base node at the position of the overlay node
+func newMergedNode(base, overlay *yaml.Node, files map[*yaml.Node]string) *yaml.Node {
+	merged := &yaml.Node{
+		Kind:    overlay.Kind,
+		Tag:     overlay.Tag,
+		Style:   overlay.Style,
+		Line:    overlay.Line,
+		Column:  overlay.Column,
+		Content: append([]*yaml.Node(nil), base.Content...),
+	}
+	files[merged] = files[overlay]
+	return merged
+}
+
+// Returns the index of a key in a mapping node, or -1 if it is missing
+func mappingIndex(n *yaml.Node, key string) int {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Returns the value of a key in a mapping node, or nil if it is missing
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	index := mappingIndex(n, key)
+	if index < 0 {
+		return nil
+	}
+	return n.Content[index+1]
+}
+
+// Returns the sorted keys of a mapping node
+func mappingKeys(n *yaml.Node) []string {
+	var keys []string
+	if n != nil && n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			keys = append(keys, n.Content[i].Value)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Prints the effective configuration as YAML, redacting resolved secrets
+func printEffectiveConfig(w io.Writer, config *Config) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, secrets.redact(buf.String()))
+	return err
+}
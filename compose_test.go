@@ -0,0 +1,171 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Writes configuration files into a temporary directory and returns its path
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadConfigComposition(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"shared/base.yaml": `endpoints:
+  - name: "Production"
+    url: "https://api.example.com/config"
+    auth: "Bearer token123"
+  - name: "Staging"
+    url: "https://staging.example.com/config"
+settings:
+  timeout: 10
+  ignoredKeys: ["id", "timestamp"]
+profiles:
+  eu:
+    endpoints:
+      - name: "Production"
+        url: "https://eu.api.example.com/config"
+    settings:
+      timeout: 20
+      ignoredKeys: ["region"]
+`,
+		"shared/modes.yaml": `settings:
+  mode: "structure"
+`,
+		"service.yaml": `include:
+  - "shared/base.yaml"
+  - "shared/modes.yaml"
+endpoints:
+  - name: "Staging"
+    url: "https://staging.example.com/v2/config"
+  - name: "Development"
+    url: "https://dev.example.com/config"
+settings:
+  ignoredKeys: ["etag", "id"]
+  jsonPath: "$.features"
+`,
+	})
+	path := filepath.Join(dir, "service.yaml")
+
+	testCases := []struct {
+		profile     string
+		urls        []string
+		ignoredKeys []string
+		timeout     int
+	}{
+		{
+			profile:     "",
+			urls:        []string{"https://api.example.com/config", "https://staging.example.com/v2/config", "https://dev.example.com/config"},
+			ignoredKeys: []string{"id", "timestamp", "etag"},
+			timeout:     10,
+		},
+		{
+			profile:     "eu",
+			urls:        []string{"https://eu.api.example.com/config", "https://staging.example.com/v2/config", "https://dev.example.com/config"},
+			ignoredKeys: []string{"id", "timestamp", "etag", "region"},
+			timeout:     20,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run("profile "+tc.profile, func(t *testing.T) {
+			config, err := LoadConfig(path, tc.profile)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var urls []string
+			for _, endpoint := range config.Endpoints {
+				urls = append(urls, endpoint.URL)
+			}
+			if !reflect.DeepEqual(urls, tc.urls) {
+				t.Errorf("Unexpected endpoints: %v", urls)
+			}
+			if config.Endpoints[0].Auth != "Bearer token123" {
+				t.Errorf("Endpoint fields not merged: %+v", config.Endpoints[0])
+			}
+			if !reflect.DeepEqual(config.GetIgnoredKeys(), tc.ignoredKeys) {
+				t.Errorf("Unexpected ignored keys: %v", config.GetIgnoredKeys())
+			}
+			if config.GetTimeout() != tc.timeout || config.GetMode() != modeStructure || config.GetJSONPath() != "$.features" {
+				t.Errorf("Unexpected settings: %+v", config.Settings)
+			}
+			if config.Include != nil || config.Profiles != nil {
+				t.Error("Includes and profiles must be resolved")
+			}
+		})
+	}
+
+	if _, err := LoadConfig(path, "us"); err == nil || !strings.Contains(err.Error(), "unknown profile: us (available: eu)") {
+		t.Errorf("Expected unknown profile error, got %v", err)
+	}
+}
+
+func TestLoadConfigCompositionProblems(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"shared.yaml": `settings:
+  ignoreKeys: ["id"]
+`,
+		"cycle.yaml": `include: ["config.yaml"]
+`,
+		"config.yaml": `include: ["shared.yaml", "cycle.yaml", "missing.yaml"]
+endpoints:
+  - name: "Production"
+    url: "https://api.example.com/config"
+  - name: "Staging"
+    url: "https://staging.example.com/config"
+`,
+	})
+
+	_, err := LoadConfig(filepath.Join(dir, "config.yaml"), "")
+	if err == nil {
+		t.Fatal("Expected error but got none")
+	}
+	message := strings.ReplaceAll(err.Error(), dir+string(filepath.Separator), "")
+	for _, want := range []string{
+		"shared.yaml:2:3: unknown field ignoreKeys in settings, did you mean ignoredKeys?",
+		"cycle.yaml:1:11: include cycle: config.yaml -> cycle.yaml -> config.yaml",
+		"config.yaml:1:40: include: stat ",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("Error does not contain %q:\n%s", want, message)
+		}
+	}
+}
+
+func TestPrintEffectiveConfig(t *testing.T) {
+	secrets.add("s3cr3t-print-token")
+	config := &Config{
+		Endpoints: []Endpoint{
+			{Name: "Production", URL: "https://api.example.com/config", Auth: "Bearer s3cr3t-print-token"},
+			{Name: "Staging", URL: "https://staging.example.com/config"},
+		},
+		Settings: Settings{Timeout: 30, IgnoredKeys: []string{"id"}},
+	}
+
+	var out strings.Builder
+	if err := printEffectiveConfig(&out, config); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "auth: Bearer [REDACTED]") || strings.Contains(out.String(), "s3cr3t") {
+		t.Errorf("Secret not redacted:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "  - name: Production\n") {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+}
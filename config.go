@@ -1,81 +1,319 @@
 package main
 
 import (
-	"errors"
-	"os"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 )
 
 // Represents the structure of the configuration file
 type Config struct {
-	Endpoints []Endpoint `yaml:"endpoints"`
-	Settings  Settings   `yaml:"settings"`
+	Include   []string          `yaml:"include,omitempty"`  // Shared fragments merged before this file
+	Profiles  map[string]Config `yaml:"profiles,omitempty"` // Named overlays selected with --profile
+	Endpoints []Endpoint        `yaml:"endpoints"`
+	Settings  Settings          `yaml:"settings"`
+	Proxy     ProxySettings     `yaml:"proxy,omitempty"`    // Shadow traffic proxy settings
+	Requests  RequestsSettings  `yaml:"requests,omitempty"` // Request corpus replayed against both endpoints
+	OpenAPI   OpenAPISettings   `yaml:"openapi,omitempty"`  // OpenAPI document used to discover requests
+
+	acceptedDifferences []AcceptedDifference // Loaded from Settings.AcceptedDifferences
+	replayRequests      []replayRequest      // Loaded from Requests.File or OpenAPI.File
+	skippedOperations   []skippedOperation   // OpenAPI operations that cannot be exercised
+	schema              *jsonschema.Schema   // Compiled from Settings.Schema
 }
 
 // Represents the configuration of an API endpoint
 type Endpoint struct {
 	Name string `yaml:"name"`
-	URL  string `yaml:"url"`
+	URL  string `yaml:"url"`            // HTTP URL, file:// path, "-" for stdin or exec: command
 	Auth string `yaml:"auth,omitempty"` // Authentication is optional
+
+	Headers    map[string]string `yaml:"headers,omitempty"`    // Additional HTTP headers sent with every request
+	OAuth2     *OAuth2Config     `yaml:"oauth2,omitempty"`     // Optional OAuth2 client credentials authentication
+	Pagination *Pagination       `yaml:"pagination,omitempty"` // Optional pagination of collection endpoints
+	Steps      []Step            `yaml:"steps,omitempty"`      // Optional chained requests, the last one is compared
+
+	JSONPath jsonPathSelectors `yaml:"jsonPath,omitempty"` // Overrides Settings.JSONPath for this endpoint
+	FieldMap map[string]string `yaml:"fieldMap,omitempty"` // Moves values from one path to another before comparing
 }
 
 // Represents comparison settings
 type Settings struct {
-	Timeout     int      `yaml:"timeout,omitempty"`
-	IgnoredKeys []string `yaml:"ignoredKeys,omitempty"`
-	JSONPath    string   `yaml:"jsonPath,omitempty"` // Optional JSON path
-}
+	Timeout     int               `yaml:"timeout,omitempty"`
+	IgnoredKeys []string          `yaml:"ignoredKeys,omitempty"`
+	JSONPath    jsonPathSelectors `yaml:"jsonPath,omitempty"` // Optional JSON path, or a list or map of named JSON paths
+	Mode        string            `yaml:"mode,omitempty"`     // Comparison mode (values or structure)
 
-// Loads the configuration file and converts it to a Config structure
-func LoadConfig(path string) (*Config, error) {
-	configFile, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
+	JSONPathResults     string `yaml:"jsonPathResults,omitempty"`     // Whether the JSON path selects one node (single) or all nodes (all)
+	AcceptedDifferences string `yaml:"acceptedDifferences,omitempty"` // Optional accepted differences file
+	NoiseSamples        int    `yaml:"noiseSamples,omitempty"`        // Number of samples fetched per endpoint to detect noise
+	Schema              string `yaml:"schema,omitempty"`              // Optional JSON Schema file validating both documents
 
+	Transforms  []Transform  `yaml:"transforms,omitempty"`  // Operations reshaping both documents before comparison, in order
+	Comparators []Comparator `yaml:"comparators,omitempty"` // Values compared by their meaning (timestamps, URLs, loose booleans and numbers)
+}
+
+// Loads the configuration file with its includes and the given profile (if any)
+// and converts it to a Config structure
+func LoadConfig(path, profile string) (*Config, error) {
+	// Compose and decode strictly, then validate, reporting every problem with its position
 	var config Config
-	err = yaml.Unmarshal(configFile, &config)
-	if err != nil {
-		return nil, err
+	root, files, problems := composeConfig(path, profile)
+	if root != nil {
+		// Invalid values are already reported by the file they come from
+		if err := root.Decode(&config); err != nil && len(problems) == 0 {
+			problems = append(problems, yamlProblems(err, root)...)
+		}
+		if err := validateConfig(&config); err != nil {
+			problems = append(problems, problemsOf(err, "")...)
+		}
+		locateProblems(root, files, problems)
 	}
-
-	// Validate configuration
-	if err := validateConfig(&config); err != nil {
-		return nil, err
+	if len(problems) > 0 {
+		sort.SliceStable(problems, func(i, j int) bool {
+			return problems[i].line < problems[j].line
+		})
+		return nil, &configError{file: path, problems: problems}
 	}
 
+	// Includes and profiles are resolved
+	config.Include, config.Profiles = nil, nil
+
 	// Set default values
 	setDefaults(&config)
 
+	// Reports an error at a configuration path
+	fail := func(configPath string, err error) (*Config, error) {
+		problems := problemsOf(err, configPath)
+		locateProblems(root, files, problems)
+		return nil, &configError{file: path, problems: problems}
+	}
+
+	var err error
+
+	// Resolve secret references relative to the configuration file
+	timeout := time.Duration(config.Settings.Timeout) * time.Second
+	if err := resolveEndpointSecrets(config.Endpoints, filepath.Dir(path), timeout); err != nil {
+		return fail("endpoints", err)
+	}
+
+	// Resolve file sources relative to the configuration file
+	for i := range config.Endpoints {
+		config.Endpoints[i].URL = resolveSource(filepath.Dir(path), config.Endpoints[i].URL)
+	}
+
+	// Load accepted differences relative to the configuration file
+	if config.Settings.AcceptedDifferences != "" {
+		acceptedPath := resolvePath(filepath.Dir(path), config.Settings.AcceptedDifferences)
+		config.acceptedDifferences, err = loadAcceptedDifferences(acceptedPath)
+		if err != nil {
+			return fail("settings.acceptedDifferences", fmt.Errorf("loading accepted differences: %w", err))
+		}
+		if groups := config.groupNames(); groups != nil {
+			for _, entry := range config.acceptedDifferences {
+				if selectorOf(entry.Path, groups) == "" {
+					return fail("settings.acceptedDifferences", fmt.Errorf("loading accepted differences: %w", groupPathError(entry.Path, groups)))
+				}
+			}
+		}
+	}
+
+	// Compile the JSON Schema relative to the configuration file
+	if config.Settings.Schema != "" {
+		config.schema, err = loadSchema(resolvePath(filepath.Dir(path), config.Settings.Schema))
+		if err != nil {
+			return fail("settings.schema", fmt.Errorf("loading schema: %w", err))
+		}
+	}
+
+	// Load the request corpus relative to the configuration file
+	if config.Requests.File != "" {
+		requestsPath := resolvePath(filepath.Dir(path), config.Requests.File)
+		config.replayRequests, err = loadReplayRequests(requestsPath, config.Requests.Format)
+		if err != nil {
+			return fail("requests.file", fmt.Errorf("loading requests: %w", err))
+		}
+	}
+
+	// Discover requests from the OpenAPI document relative to the configuration file
+	if config.OpenAPI.File != "" {
+		openAPIPath := resolvePath(filepath.Dir(path), config.OpenAPI.File)
+		config.replayRequests, config.skippedOperations, err = loadOpenAPIRequests(openAPIPath, config.OpenAPI.Examples)
+		if err != nil {
+			return fail("openapi.file", fmt.Errorf("loading OpenAPI document: %w", err))
+		}
+	}
+
 	return &config, nil
 }
 
-// Validates the configuration
+// Resolves a path relative to the given base directory
+func resolvePath(baseDir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+// Validates the configuration, reporting all problems at once
 func validateConfig(config *Config) error {
+	var problems configProblems
+
 	// Check the number of endpoints
 	if len(config.Endpoints) < 2 {
-		return errors.New("at least two endpoints are required for comparison")
+		problems.add("endpoints", "at least two endpoints are required for comparison")
 	}
 
-	// Check if endpoint URL is set
+	// Check every endpoint
+	stdinSources := 0
+	names := make(map[string]bool)
 	for i, endpoint := range config.Endpoints {
-		if endpoint.Name == "" {
-			return errors.New("endpoint name is required")
+		path := fmt.Sprintf("endpoints[%d]", i)
+		name := endpoint.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+			problems.add(path+".name", "endpoint name is required")
+		} else if names[name] {
+			problems.add(path+".name", "duplicate endpoint name: %s", name)
 		}
+		names[name] = true
+
 		if endpoint.URL == "" {
-			return errors.New("endpoint URL is required")
+			problems.add(path+".url", "endpoint %s: URL is required", name)
+		} else if err := validateSource(endpoint.URL); err != nil {
+			problems.add(path+".url", "endpoint %s: %v", name, err)
+		}
+		isHTTP := isHTTPSource(endpoint.URL)
+
+		if len(endpoint.Headers) > 0 && !isHTTP {
+			problems.add(path+".headers", "endpoint %s: headers require an http or https URL", name)
+		}
+		if endpoint.OAuth2 != nil {
+			if !isHTTP {
+				problems.add(path+".oauth2", "endpoint %s: oauth2 requires an http or https URL", name)
+			}
+			if endpoint.Auth != "" {
+				problems.add(path+".auth", "endpoint %s: auth and oauth2 cannot be used together", name)
+			}
+			if err := endpoint.OAuth2.validate(); err != nil {
+				problems.add(path+".oauth2", "endpoint %s: %v", name, err)
+			}
+		}
+		if len(endpoint.Steps) > 0 {
+			if !isHTTP {
+				problems.add(path+".steps", "endpoint %s: steps require an http or https URL", name)
+			}
+			if endpoint.Pagination != nil {
+				problems.add(path+".steps", "endpoint %s: steps and pagination cannot be used together", name)
+			}
+			if err := validateSteps(endpoint.Steps); err != nil {
+				problems.add(path+".steps", "endpoint %s: %v", name, err)
+			}
+		}
+		if endpoint.Pagination != nil {
+			if !isHTTP {
+				problems.add(path+".pagination", "endpoint %s: pagination requires an http or https URL", name)
+			}
+			if err := endpoint.Pagination.validate(); err != nil {
+				problems.add(path+".pagination", "endpoint %s: %v", name, err)
+			}
+		}
+
+		var selectorProblems configProblems
+		endpoint.JSONPath.validate(path+".jsonPath", &selectorProblems)
+		for _, p := range selectorProblems {
+			problems.add(p.path, "endpoint %s: %s", name, p.message)
+		}
+		if err := validateFieldMap(endpoint.FieldMap); err != nil {
+			problems.add(path+".fieldMap", "endpoint %s: %v", name, err)
+		}
+		if selectors := config.endpointSelectors(endpoint); selectors.grouped() {
+			for _, from := range sortedSources(endpoint.FieldMap) {
+				for _, fieldPath := range []string{from, endpoint.FieldMap[from]} {
+					if !fieldMapAddressesGroup(fieldPath, selectors.names()) {
+						problems.add(path+".fieldMap", "endpoint %s: fieldMap: %v", name, groupPathError(fieldPath, selectors.names()))
+					}
+				}
+			}
 		}
 
-		// Check for duplicate endpoint names
-		for j := i + 1; j < len(config.Endpoints); j++ {
-			if endpoint.Name == config.Endpoints[j].Name {
-				return errors.New("duplicate endpoint name: " + endpoint.Name)
+		// Standard input can only be read once
+		if endpoint.URL == stdinSource {
+			stdinSources++
+			if stdinSources == 2 {
+				problems.add(path+".url", "only one endpoint can read from standard input")
 			}
 		}
 	}
 
-	return nil
+	// Check the JSON path syntax
+	config.Settings.JSONPath.validate("settings.jsonPath", &problems)
+
+	switch config.Settings.JSONPathResults {
+	case "", jsonPathSingle, jsonPathAll:
+	default:
+		problems.add("settings.jsonPathResults", "unknown jsonPathResults: %s (expected %s or %s)", config.Settings.JSONPathResults, jsonPathSingle, jsonPathAll)
+	}
+	if err := config.checkNodeSelectors(); err != nil {
+		problems.add("settings.jsonPathResults", "%v", err)
+	}
+
+	// Check comparison mode
+	switch config.Settings.Mode {
+	case "", modeValues, modeStructure:
+	default:
+		problems.add("settings.mode", "unknown comparison mode: %s", config.Settings.Mode)
+	}
+
+	// Check transforms
+	for i := range config.Settings.Transforms {
+		if err := config.Settings.Transforms[i].validate(); err != nil {
+			problems.add(fmt.Sprintf("settings.transforms[%d]", i), "transform %d: %v", i+1, err)
+		}
+	}
+
+	// Check comparators
+	groups := config.groupNames()
+	for i, c := range config.Settings.Comparators {
+		if err := c.validate(); err != nil {
+			problems.add(fmt.Sprintf("settings.comparators[%d]", i), "comparator %d: %v", i+1, err)
+		} else if groups != nil && !jsonPathAddressesGroup(c.Path, groups) {
+			problems.add(fmt.Sprintf("settings.comparators[%d]", i), "comparator %d: %v", i+1, groupPathError(c.Path, groups))
+		}
+	}
+
+	// Check noise samples
+	if config.Settings.NoiseSamples < 0 {
+		problems.add("settings.noiseSamples", "noiseSamples must not be negative")
+	}
+	if config.Settings.NoiseSamples > 1 && stdinSources > 0 {
+		problems.add("settings.noiseSamples", "noiseSamples cannot be used with standard input")
+	}
+
+	// Check proxy settings
+	if err := validateProxySettings(&config.Proxy); err != nil {
+		problems.add("proxy", "%v", err)
+	}
+
+	// Check request corpus settings
+	if err := validateRequestsSettings(&config.Requests, config.Endpoints); err != nil {
+		problems.add("requests", "%v", err)
+	}
+	if config.OpenAPI.File != "" {
+		if config.Requests.File != "" {
+			problems.add("openapi", "requests and openapi cannot be used together")
+		}
+		for i, endpoint := range config.Endpoints {
+			if endpoint.URL != "" && !isHTTPSource(endpoint.URL) {
+				problems.add(fmt.Sprintf("endpoints[%d].url", i), "endpoint %s must be an http or https URL to use openapi", endpoint.Name)
+			}
+		}
+	}
+
+	return problems.err()
 }
 
 // Sets default values
@@ -90,6 +328,33 @@ func setDefaults(config *Config) {
 		config.Settings.IgnoredKeys = []string{}
 	}
 
+	// Default comparison mode
+	if config.Settings.Mode == "" {
+		config.Settings.Mode = modeValues
+	}
+
+	// JSON paths select a single node by default
+	if config.Settings.JSONPathResults == "" {
+		config.Settings.JSONPathResults = jsonPathSingle
+	}
+
+	// Default pagination values
+	for _, endpoint := range config.Endpoints {
+		if endpoint.Pagination != nil {
+			endpoint.Pagination.setDefaults()
+		}
+	}
+
+	// Default number of samples (no noise detection)
+	if config.Settings.NoiseSamples == 0 {
+		config.Settings.NoiseSamples = 1
+	}
+
+	// Default number of requests replayed concurrently
+	if config.Requests.Concurrency == 0 {
+		config.Requests.Concurrency = defaultReplayConcurrency
+	}
+
 	// Default JSON path is empty string (compare entire response)
 }
 
@@ -108,7 +373,129 @@ func (c *Config) GetIgnoredKeys() []string {
 	return c.Settings.IgnoredKeys
 }
 
-// Returns the JSON path
+// Returns the JSON path, or the named JSON paths separated by commas
 func (c *Config) GetJSONPath() string {
+	return c.Settings.JSONPath.String()
+}
+
+// Returns the JSON path selectors
+func (c *Config) GetJSONPathSelectors() jsonPathSelectors {
 	return c.Settings.JSONPath
 }
+
+// Returns how the results of the JSON path are compared
+func (c *Config) GetJSONPathResults() string {
+	return c.Settings.JSONPathResults
+}
+
+// Reports whether the data extracted for an endpoint holds all selected nodes keyed by their paths
+func (c *Config) selectsNodes(endpoint Endpoint) bool {
+	selectors := c.endpointSelectors(endpoint)
+	return c.GetJSONPathResults() == jsonPathAll && len(selectors) > 0 && !selectors.grouped()
+}
+
+// Reports whether an endpoint reads standard input, which can only be read once
+func (c *Config) readsStdin() bool {
+	for _, endpoint := range c.Endpoints {
+		if endpoint.URL == stdinSource {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks that all endpoints use the same JSON paths when all results are compared, since nodes are keyed
+// by their absolute locations and nodes selected by different paths could never match
+func (c *Config) checkNodeSelectors() error {
+	if c.Settings.JSONPathResults != jsonPathAll || len(c.Endpoints) < 2 {
+		return nil
+	}
+	first := c.endpointSelectors(c.Endpoints[0])
+	for _, endpoint := range c.Endpoints[1:] {
+		if c.endpointSelectors(endpoint).String() != first.String() {
+			return fmt.Errorf("endpoints %s and %s use different jsonPath expressions, which cannot be compared with jsonPathResults %s",
+				c.Endpoints[0].Name, endpoint.Name, jsonPathAll)
+		}
+	}
+	return nil
+}
+
+// Returns the selector names of the endpoints comparing grouped documents, or nil if no document is grouped
+func (c *Config) groupNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, endpoint := range c.Endpoints {
+		selectors := c.endpointSelectors(endpoint)
+		if !selectors.grouped() {
+			continue
+		}
+		for _, name := range selectors.names() {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// Returns the JSON path selectors of an endpoint, which override the settings
+func (c *Config) endpointSelectors(endpoint Endpoint) jsonPathSelectors {
+	if len(endpoint.JSONPath) > 0 {
+		return endpoint.JSONPath
+	}
+	return c.GetJSONPathSelectors()
+}
+
+// Returns the transforms applied to both documents
+func (c *Config) GetTransforms() []Transform {
+	return c.Settings.Transforms
+}
+
+// Returns the comparators applied to the selected values
+func (c *Config) GetComparators() []Comparator {
+	return c.Settings.Comparators
+}
+
+// Returns the fraction of requests mirrored by the proxy, keeping an explicit 0
+func (c *Config) GetProxySampleRate() float64 {
+	if c.Proxy.SampleRate == nil {
+		return defaultProxySampleRate
+	}
+	return *c.Proxy.SampleRate
+}
+
+// Returns the comparison mode
+func (c *Config) GetMode() string {
+	return c.Settings.Mode
+}
+
+// Returns the accepted differences
+func (c *Config) GetAcceptedDifferences() []AcceptedDifference {
+	return c.acceptedDifferences
+}
+
+// Returns the number of samples fetched per endpoint
+func (c *Config) GetNoiseSamples() int {
+	return c.Settings.NoiseSamples
+}
+
+// Reports whether a request corpus is replayed instead of fetching the endpoint URLs
+func (c *Config) HasReplayRequests() bool {
+	return c.Requests.File != "" || c.OpenAPI.File != ""
+}
+
+// Returns the requests replayed against both endpoints
+func (c *Config) GetReplayRequests() []replayRequest {
+	return c.replayRequests
+}
+
+// Returns the OpenAPI operations that cannot be exercised
+func (c *Config) GetSkippedOperations() []skippedOperation {
+	return c.skippedOperations
+}
+
+// Returns the compiled JSON Schema, or nil if none is configured
+func (c *Config) GetSchema() *jsonschema.Schema {
+	return c.schema
+}
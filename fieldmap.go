@@ -0,0 +1,235 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Represents a segment of a document path: an object key or an array index
+type pathSegment struct {
+	key   string
+	index int // Used when key is empty
+}
+
+// Parses a document path in the format of reported differences, e.g. data.items[0].name
+func parseDocumentPath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	rest := path
+	for rest != "" {
+		switch {
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: missing ]", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid path %q: invalid index %s", path, rest[1:end])
+			}
+			segments = append(segments, pathSegment{index: index})
+			rest = rest[end+1:]
+		default:
+			if len(segments) > 0 {
+				if rest[0] != '.' {
+					return nil, fmt.Errorf("invalid path %q", path)
+				}
+				rest = rest[1:]
+			}
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid path %q: empty key", path)
+			}
+			segments = append(segments, pathSegment{key: rest[:end]})
+			rest = rest[end:]
+		}
+	}
+
+	if len(segments) == 0 {
+		return nil, errors.New("path is empty")
+	}
+	return segments, nil
+}
+
+// Validates the paths of a field map, which must end with an object key and have distinct targets
+func validateFieldMap(fieldMap map[string]string) error {
+	targets := make(map[string]string)
+	for _, from := range sortedSources(fieldMap) {
+		to := fieldMap[from]
+		for _, path := range []string{from, to} {
+			segments, err := parseDocumentPath(path)
+			if err != nil {
+				return fmt.Errorf("fieldMap: %w", err)
+			}
+			if segments[len(segments)-1].key == "" {
+				return fmt.Errorf("fieldMap: path %q must end with a key", path)
+			}
+		}
+		if other, ok := targets[to]; ok {
+			return fmt.Errorf("fieldMap: %s and %s are both mapped to %s", other, from, to)
+		}
+		targets[to] = from
+	}
+	return nil
+}
+
+// Moves the values of a document from the source to the target paths of a field map.
+// Missing source paths are skipped, so the difference shows up at the target path.
+func applyFieldMap(data interface{}, fieldMap map[string]string) (interface{}, error) {
+	if len(fieldMap) == 0 {
+		return data, nil
+	}
+
+	// Work on a copy so that the fetched document is left untouched
+	data = copyJSONValue(data)
+
+	// Remove all source values first, so that fields can be swapped
+	sources := sortedSources(fieldMap)
+	values := make(map[string]interface{})
+	for _, from := range sources {
+		segments, err := parseDocumentPath(from)
+		if err != nil {
+			return nil, err
+		}
+		if value, ok := removeDocumentValue(data, segments); ok {
+			values[from] = value
+		}
+	}
+
+	for _, from := range sources {
+		value, ok := values[from]
+		if !ok {
+			continue
+		}
+		segments, err := parseDocumentPath(fieldMap[from])
+		if err != nil {
+			return nil, err
+		}
+		if data, err = setDocumentValue(data, segments, value); err != nil {
+			return nil, fmt.Errorf("mapping %s to %s: %w", from, fieldMap[from], err)
+		}
+	}
+
+	return data, nil
+}
+
+// Returns the source paths of a field map in a stable order
+func sortedSources(fieldMap map[string]string) []string {
+	sources := make([]string, 0, len(fieldMap))
+	for from := range fieldMap {
+		sources = append(sources, from)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// Removes the value at a path ending with a key and reports whether it existed.
+// Objects left empty by the removal are removed as well, as they only held the moved value.
+func removeDocumentValue(data interface{}, segments []pathSegment) (interface{}, bool) {
+	parents := []interface{}{data}
+	for _, segment := range segments[:len(segments)-1] {
+		child, ok := documentChild(parents[len(parents)-1], segment)
+		if !ok {
+			return nil, false
+		}
+		parents = append(parents, child)
+	}
+
+	object, ok := parents[len(parents)-1].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	key := segments[len(segments)-1].key
+	value, ok := object[key]
+	if !ok {
+		return nil, false
+	}
+	delete(object, key)
+
+	for i := len(parents) - 1; i > 0 && segments[i-1].key != ""; i-- {
+		if emptied, isObject := parents[i].(map[string]interface{}); !isObject || len(emptied) > 0 {
+			break
+		}
+		parent, isObject := parents[i-1].(map[string]interface{})
+		if !isObject {
+			break
+		}
+		delete(parent, segments[i-1].key)
+	}
+	return value, true
+}
+
+// Sets the value at a path ending with a key, creating missing objects on the way
+func setDocumentValue(data interface{}, segments []pathSegment, value interface{}) (interface{}, error) {
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+
+	parent := data
+	for _, segment := range segments[:len(segments)-1] {
+		child, ok := documentChild(parent, segment)
+		if !ok || child == nil {
+			object, isObject := parent.(map[string]interface{})
+			if segment.key == "" || !isObject {
+				return nil, errors.New("target path does not exist")
+			}
+			child = make(map[string]interface{})
+			object[segment.key] = child
+		}
+		parent = child
+	}
+
+	object, ok := parent.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("target path is not in an object")
+	}
+	key := segments[len(segments)-1].key
+	if _, exists := object[key]; exists {
+		return nil, errors.New("target path already exists")
+	}
+	object[key] = value
+	return data, nil
+}
+
+// Returns the child of an object or array for a path segment
+func documentChild(data interface{}, segment pathSegment) (interface{}, bool) {
+	if segment.key != "" {
+		object, ok := data.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		child, ok := object[segment.key]
+		return child, ok
+	}
+
+	array, ok := data.([]interface{})
+	if !ok || segment.index >= len(array) {
+		return nil, false
+	}
+	return array[segment.index], true
+}
+
+// Returns a deep copy of a decoded JSON value
+func copyJSONValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			result[key] = copyJSONValue(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = copyJSONValue(item)
+		}
+		return result
+	default:
+		return v
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDocumentPath(t *testing.T) {
+	testCases := []struct {
+		path      string
+		want      []pathSegment
+		wantError bool
+	}{
+		{path: "name", want: []pathSegment{{key: "name"}}},
+		{path: "data.items[2].id", want: []pathSegment{{key: "data"}, {key: "items"}, {index: 2}, {key: "id"}}},
+		{path: "[0].name", want: []pathSegment{{index: 0}, {key: "name"}}},
+		{path: "", wantError: true},
+		{path: "data..id", wantError: true},
+		{path: "items[x]", wantError: true},
+		{path: "items[0", wantError: true},
+		{path: "items[0]name", wantError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			got, err := parseDocumentPath(tc.path)
+			if tc.wantError {
+				if err == nil {
+					t.Errorf("Expected error but got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestApplyFieldMap(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		fieldMap map[string]string
+		want     string
+		errorMsg string
+	}{
+		{
+			name:     "rename key",
+			data:     `{"hostname": "db", "port": 5432}`,
+			fieldMap: map[string]string{"hostname": "host"},
+			want:     `{"host": "db", "port": 5432}`,
+		},
+		{
+			name:     "move between nested objects",
+			data:     `{"db": {"host": "db", "port": 5432}}`,
+			fieldMap: map[string]string{"db.host": "database.connection.host", "db.port": "database.port"},
+			want:     `{"database": {"connection": {"host": "db"}, "port": 5432}}`,
+		},
+		{
+			name:     "keep objects with other fields",
+			data:     `{"db": {"host": "db", "port": 5432}}`,
+			fieldMap: map[string]string{"db.host": "hostname"},
+			want:     `{"db": {"port": 5432}, "hostname": "db"}`,
+		},
+		{
+			name:     "inside array element",
+			data:     `{"items": [{"label": "a"}, {"label": "b"}]}`,
+			fieldMap: map[string]string{"items[1].label": "items[1].name"},
+			want:     `{"items": [{"label": "a"}, {"name": "b"}]}`,
+		},
+		{
+			name:     "swap keys",
+			data:     `{"a": 1, "b": 2}`,
+			fieldMap: map[string]string{"a": "b", "b": "a"},
+			want:     `{"a": 2, "b": 1}`,
+		},
+		{
+			name:     "missing source is skipped",
+			data:     `{"host": "db"}`,
+			fieldMap: map[string]string{"hostname": "host", "db.port": "port"},
+			want:     `{"host": "db"}`,
+		},
+		{
+			name:     "existing target",
+			data:     `{"hostname": "db", "host": "other"}`,
+			fieldMap: map[string]string{"hostname": "host"},
+			errorMsg: "target path already exists",
+		},
+		{
+			name:     "target through array",
+			data:     `{"hostname": "db"}`,
+			fieldMap: map[string]string{"hostname": "hosts[0].name"},
+			errorMsg: "target path does not exist",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := parseJSON([]byte(tc.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			original := copyJSONValue(data)
+
+			got, err := applyFieldMap(data, tc.fieldMap)
+			if tc.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tc.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			want, _ := parseJSON([]byte(tc.want))
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected %v, got %v", want, got)
+			}
+			if !reflect.DeepEqual(data, original) {
+				t.Errorf("Input was modified: %v", data)
+			}
+		})
+	}
+}
+
+func TestValidateFieldMap(t *testing.T) {
+	testCases := []struct {
+		name      string
+		fieldMap  map[string]string
+		wantError bool
+	}{
+		{name: "valid", fieldMap: map[string]string{"config.name": "data.configuration.displayName"}},
+		{name: "empty", fieldMap: nil},
+		{name: "invalid path", fieldMap: map[string]string{"config..name": "name"}, wantError: true},
+		{name: "target ends with index", fieldMap: map[string]string{"name": "names[0]"}, wantError: true},
+		{name: "duplicate target", fieldMap: map[string]string{"a": "c", "b": "c"}, wantError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateFieldMap(tc.fieldMap)
+			if tc.wantError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tc.wantError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestProcessJSONDataPerEndpoint(t *testing.T) {
+	v1, _ := parseJSON([]byte(`{"config": {"name": "app", "replicas": 3}}`))
+	v2, _ := parseJSON([]byte(`{"data": {"configuration": {"displayName": "app", "replicas": 3}}}`))
+
+	endpointA := Endpoint{Name: "v1"}
+	endpointB := Endpoint{
+		Name:     "v2",
+		JSONPath: singleSelector("$.data.configuration"),
+		FieldMap: map[string]string{"displayName": "name"},
+	}
+
+	dataA, dataB, err := processJSONData(endpointA, endpointB, v1, v2, singleSelector("$.config"), jsonPathSingle)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if equal, diffs := compareJSON(dataA, dataB, nil); !equal {
+		t.Errorf("Expected equivalent documents, got %v", diffs)
+	}
+
+	endpointB.JSONPath = singleSelector("$.missing")
+	if _, _, err := processJSONData(endpointA, endpointB, v1, v2, singleSelector("$.config"), jsonPathSingle); err == nil || !strings.Contains(err.Error(), "endpoint B") {
+		t.Errorf("Expected error for endpoint B, got %v", err)
+	}
+}
+
+func TestCheckNodeSelectors(t *testing.T) {
+	config := &Config{
+		Endpoints: []Endpoint{
+			{Name: "v1", URL: "file://v1.json"},
+			{Name: "v2", URL: "file://v2.json", JSONPath: singleSelector("$.data.configuration..x")},
+		},
+		Settings: Settings{JSONPath: singleSelector("$.config..x")},
+	}
+
+	// A single result is compared wherever it was selected
+	if err := config.checkNodeSelectors(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// All results are keyed by absolute locations, which differ between the paths
+	config.Settings.JSONPathResults = jsonPathAll
+	if err := config.checkNodeSelectors(); err == nil || !strings.Contains(err.Error(), "v1 and v2") {
+		t.Errorf("Expected error naming the endpoints, got %v", err)
+	}
+
+	config.Endpoints[1].JSONPath = singleSelector("$.config..x")
+	if err := config.checkNodeSelectors(); err != nil {
+		t.Errorf("Unexpected error for identical paths: %v", err)
+	}
+}
@@ -4,6 +4,7 @@ go 1.24.2
 
 require (
 	github.com/google/go-cmp v0.7.0
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
 	github.com/theory/jsonpath v0.10.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -1,27 +1,75 @@
 package main
 
 import (
+	"bytes"
 	"io"
 	"net/http"
 	"time"
 )
 
-// Fetches JSON data from the specified URL and converts it to a map
-func fetchJSON(url, auth string, timeout time.Duration) (map[string]interface{}, error) {
+// Represents a raw HTTP response
+type rawResponse struct {
+	body        []byte
+	statusCode  int
+	contentType string
+	header      http.Header
+}
+
+// Represents an HTTP request sent to an endpoint
+type httpRequest struct {
+	method  string
+	url     string
+	auth    string
+	body    []byte
+	headers map[string]string
+}
+
+// Fetches the raw response body from the specified HTTP URL with the endpoint's authentication
+func fetchHTTP(endpoint Endpoint, url string, timeout time.Duration) (*rawResponse, error) {
+	return sendWithAuth(endpoint, httpRequest{method: http.MethodGet, url: url}, timeout)
+}
+
+// Returns the endpoint headers overridden by the request headers
+func withEndpointHeaders(endpoint Endpoint, headers map[string]string) map[string]string {
+	if len(endpoint.Headers) == 0 {
+		return headers
+	}
+
+	merged := make(map[string]string, len(endpoint.Headers)+len(headers))
+	for name, value := range endpoint.Headers {
+		merged[name] = value
+	}
+	for name, value := range headers {
+		merged[name] = value
+	}
+	return merged
+}
+
+// Sends an HTTP request and reads the raw response
+func sendHTTP(request httpRequest, timeout time.Duration) (*rawResponse, error) {
 	// Set up HTTP client
 	client := &http.Client{
 		Timeout: timeout,
 	}
 
 	// Create request
-	req, err := http.NewRequest("GET", url, nil)
+	var body io.Reader
+	if request.body != nil {
+		body = bytes.NewReader(request.body)
+	}
+	req, err := http.NewRequest(request.method, request.url, body)
 	if err != nil {
 		return nil, err
 	}
 
+	// Set additional headers
+	for name, value := range request.headers {
+		req.Header.Set(name, value)
+	}
+
 	// Set authentication header if provided
-	if auth != "" {
-		req.Header.Set("Authorization", auth)
+	if request.auth != "" {
+		req.Header.Set("Authorization", request.auth)
 	}
 
 	// Set Accept header
@@ -35,11 +83,15 @@ func fetchJSON(url, auth string, timeout time.Duration) (map[string]interface{},
 	defer resp.Body.Close()
 
 	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
-	// Parse JSON
-	return parseJSON(body)
+	return &rawResponse{
+		body:        respBody,
+		statusCode:  resp.StatusCode,
+		contentType: resp.Header.Get("Content-Type"),
+		header:      resp.Header,
+	}, nil
 }
@@ -8,9 +8,15 @@ import (
 	"github.com/theory/jsonpath"
 )
 
-// Converts a JSON string to a map
-func parseJSON(data []byte) (map[string]interface{}, error) {
-	var result map[string]interface{}
+// How the results of a JSONPath expression are compared
+const (
+	jsonPathSingle = "single" // The expression must select exactly one node
+	jsonPathAll    = "all"    // All selected nodes are compared, keyed by their normalized paths
+)
+
+// Converts a JSON string to a value (object, array or primitive)
+func parseJSON(data []byte) (interface{}, error) {
+	var result interface{}
 	err := json.Unmarshal(data, &result)
 	if err != nil {
 		return nil, err
@@ -18,9 +24,23 @@ func parseJSON(data []byte) (map[string]interface{}, error) {
 	return result, nil
 }
 
+// Converts a decoded value (e.g. from YAML) into the representation produced by JSON parsing
+func normalizeJSONValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // Extracts a value from a JSON object at the specified JSONPath
 // Path should be a valid JSONPath expression like "$.settings.timeout" or "$..name"
-func extractPath(data map[string]interface{}, path string) (interface{}, error) {
+func extractPath(data interface{}, path string) (interface{}, error) {
 	if path == "" {
 		return data, nil
 	}
@@ -42,8 +62,24 @@ func extractPath(data map[string]interface{}, path string) (interface{}, error)
 	// For comparison purposes, we expect a single result
 	// If multiple results are found, return an error
 	if len(results) > 1 {
-		return nil, fmt.Errorf("JSONPath returned multiple results (%d), expected single result", len(results))
+		return nil, fmt.Errorf("JSONPath returned multiple results (%d), expected single result (set jsonPathResults to all to compare them by location)", len(results))
 	}
 
 	return results[0], nil
 }
+
+// Extracts all values selected by a JSONPath expression, keyed by their normalized paths, e.g. $['items'][0].
+// Nodes selected on only one side are then reported as missing on the other.
+func extractNodes(data interface{}, path string) (map[string]interface{}, error) {
+	p, err := jsonpath.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSONPath expression: %w", err)
+	}
+
+	// Descendant selectors may select the same node more than once
+	nodes := make(map[string]interface{})
+	for _, node := range p.SelectLocated(data).Deduplicate() {
+		nodes[node.Path.String()] = node.Node
+	}
+	return nodes, nil
+}
@@ -1,68 +1,188 @@
 package main
 
 import (
-	"flag"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"strings"
 	"time"
 )
 
 func main() {
-	// Parse command line arguments
-	flag.Parse()
+	// Keep resolved secrets out of log output
+	log.SetOutput(redactingWriter{os.Stderr})
 
-	// Check positional arguments
-	args := flag.Args()
-	if len(args) != 1 {
-		fmt.Fprintf(os.Stderr, "Usage: %s config.yaml\n", os.Args[0])
+	args := os.Args[1:]
+	if len(args) == 0 {
+		printUsage(os.Stderr)
 		os.Exit(2)
 	}
 
-	configPath := args[0]
+	// Dispatch subcommands
+	if cmd, ok := findCommand(args[0]); ok {
+		os.Exit(cmd.run(args[1:]))
+	}
+	if args[0] == "-h" || args[0] == "--help" {
+		os.Exit(runHelp(nil))
+	}
+
+	// Without a subcommand, the arguments are those of compare
+	os.Exit(runCompare(args))
+}
 
-	// Load configuration file
-	config, err := LoadConfig(configPath)
+// Runs the comparison of a configuration file or two sources and returns the exit code
+func runCompare(args []string) int {
+	fs := newCommandFlagSet("compare", "config.yaml [flags]", "SOURCE_A SOURCE_B [flags]")
+	profile := profileFlag(fs)
+	printConfig := fs.Bool("print-config", false, "print the effective configuration and exit")
+	verbose := fs.Bool("verbose", false, "print the compared documents after extraction and transforms")
+	var o overrides
+	o.register(fs)
+
+	positional, err := parseInterspersed(fs, args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config file: %v\n", err)
-		os.Exit(2)
+		return flagErrorCode(err)
 	}
 
-	// Fetch endpoints and timeout
-	endpointA, endpointB := config.GetDefaultEndpoints()
-	timeout := config.GetTimeout()
+	// Load configuration file or compare two sources without one
+	var config *Config
+	switch len(positional) {
+	case 1:
+		config, err = LoadConfig(positional[0], *profile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config file: %v\n", err)
+			return 2
+		}
+	case 2:
+		config, err = newAdHocConfig(positional[0], positional[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	// Apply command line overrides
+	if err := o.apply(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	// Show the configuration after includes, profile and overrides instead of comparing
+	if *printConfig {
+		if err := printEffectiveConfig(os.Stdout, config); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		return 0
+	}
 
 	// Display endpoint information
-	fmt.Sprintf("Comparing:\n  A: %s (%s)\n  B: %s (%s)\n\n",
+	endpointA, endpointB := config.GetDefaultEndpoints()
+	fmt.Printf("Comparing:\n  A: %s (%s)\n  B: %s (%s)\n\n",
 		endpointA.Name, endpointA.URL, endpointB.Name, endpointB.URL)
 
+	// Replay the request corpus or discovered operations against both endpoints
+	if config.HasReplayRequests() {
+		code := reportReplay(runReplay(config, config.GetReplayRequests()))
+		reportSkippedOperations(config.GetSkippedOperations())
+		return code
+	}
+
+	// Compare JSON from both endpoints
+	result, err := compareEndpoints(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, secrets.redact(err.Error()))
+		return 2
+	}
+
+	if *verbose {
+		if err := printDocuments(os.Stdout, endpointA, endpointB, result); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+
+	return reportResults(result)
+}
+
+// Fetches, extracts and compares the default endpoints
+func compareEndpoints(config *Config) (comparisonResult, error) {
+	endpointA, endpointB := config.GetDefaultEndpoints()
+
 	// Fetch JSON from both endpoints
-	jsonA, jsonB, err := fetchEndpointData(endpointA, endpointB, timeout)
+	jsonA, jsonB, err := fetchEndpointData(endpointA, endpointB, config.GetTimeout())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(2)
+		return comparisonResult{}, err
 	}
 
 	// Process JSON data based on path
-	dataA, dataB, err := processJSONData(jsonA, jsonB, config.GetJSONPath())
+	dataA, dataB, err := processJSONData(endpointA, endpointB, jsonA, jsonB, config.GetJSONPathSelectors(), config.GetJSONPathResults())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(2)
+		return comparisonResult{}, err
+	}
+
+	// Validate both documents against the schema
+	var violations []schemaViolation
+	if schema := config.GetSchema(); schema != nil {
+		for _, doc := range []struct {
+			endpoint Endpoint
+			data     interface{}
+		}{{endpointA, dataA}, {endpointB, dataB}} {
+			validate := validateSchema
+			if config.selectsNodes(doc.endpoint) {
+				validate = validateNodes
+			}
+			found, err := validate(schema, doc.endpoint.Name, doc.data)
+			if err != nil {
+				return comparisonResult{}, err
+			}
+			violations = append(violations, found...)
+		}
+	}
+
+	// Reshape both documents before comparing them
+	dataA, dataB, err = transformDocuments(dataA, dataB, config.GetTransforms())
+	if err != nil {
+		return comparisonResult{}, err
+	}
+
+	// Detect non-deterministic paths by sampling each endpoint again
+	opts := newCompareOptions(config)
+	var noisy []noisyPath
+	if config.GetNoiseSamples() > 1 {
+		noisy, err = detectNoisyPaths(config, endpointA, endpointB, dataA, dataB, opts)
+		if err != nil {
+			return comparisonResult{}, err
+		}
 	}
 
 	// Compare JSON
-	compareAndReportResults(dataA, dataB, config.GetIgnoredKeys())
+	diffs := filterNoisyDiffs(diffJSON(dataA, dataB, opts), noisy)
+	return comparisonResult{
+		acceptanceResult: classifyDifferences(diffs, config.GetAcceptedDifferences(), time.Now()),
+		noisy:            noisy,
+		violations:       violations,
+		documentA:        dataA,
+		documentB:        dataB,
+		selectors:        selectorNames(config.endpointSelectors(endpointA), config.endpointSelectors(endpointB)),
+	}, nil
 }
 
 // Fetches JSON from both endpoints
-func fetchEndpointData(endpointA, endpointB Endpoint, timeout int) (map[string]interface{}, map[string]interface{}, error) {
+func fetchEndpointData(endpointA, endpointB Endpoint, timeout int) (interface{}, interface{}, error) {
 	// Fetch from endpoint A
-	jsonA, err := fetchJSON(endpointA.URL, endpointA.Auth, time.Duration(timeout)*time.Second)
+	jsonA, err := fetchJSON(endpointA, time.Duration(timeout)*time.Second)
 	if err != nil {
 		return nil, nil, fmt.Errorf("Error fetching from endpoint A: %v", err)
 	}
 
 	// Fetch from endpoint B
-	jsonB, err := fetchJSON(endpointB.URL, endpointB.Auth, time.Duration(timeout)*time.Second)
+	jsonB, err := fetchJSON(endpointB, time.Duration(timeout)*time.Second)
 	if err != nil {
 		return nil, nil, fmt.Errorf("Error fetching from endpoint B: %v", err)
 	}
@@ -70,41 +190,194 @@ func fetchEndpointData(endpointA, endpointB Endpoint, timeout int) (map[string]i
 	return jsonA, jsonB, nil
 }
 
-// Extracts data based on JSON path
-func processJSONData(jsonA, jsonB map[string]interface{}, jsonPath string) (interface{}, interface{}, error) {
-	// Use entire JSON if no path specified
-	if jsonPath == "" {
-		return jsonA, jsonB, nil
+// Extracts the compared data of both endpoints, using their JSON paths and field maps
+func processJSONData(endpointA, endpointB Endpoint, jsonA, jsonB interface{}, selectors jsonPathSelectors, results string) (interface{}, interface{}, error) {
+	// Process endpoint A
+	extractedA, err := extractEndpointData(endpointA, jsonA, selectors, results)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error processing endpoint A: %v", err)
 	}
 
-	// Extract from endpoint A
-	extractedA, err := extractPath(jsonA, jsonPath)
+	// Process endpoint B
+	extractedB, err := extractEndpointData(endpointB, jsonB, selectors, results)
 	if err != nil {
-		return nil, nil, fmt.Errorf("Error extracting JSON path from endpoint A: %v", err)
+		return nil, nil, fmt.Errorf("Error processing endpoint B: %v", err)
+	}
+
+	return extractedA, extractedB, nil
+}
+
+// Extracts data with the endpoint's JSON paths (or the default ones) and applies its field map.
+// Named selectors are extracted from the same document into an object keyed by their names.
+// When all results of a single JSON path are compared, the field map applies to each selected node.
+func extractEndpointData(endpoint Endpoint, data interface{}, selectors jsonPathSelectors, results string) (interface{}, error) {
+	if len(endpoint.JSONPath) > 0 {
+		selectors = endpoint.JSONPath
+	}
+
+	if selectors.grouped() {
+		groups := make(map[string]interface{}, len(selectors))
+		for _, selector := range selectors {
+			extracted, err := extractSelection(data, selector.Path, results)
+			if err != nil {
+				return nil, fmt.Errorf("extracting JSON path %s: %v", selector.Name, err)
+			}
+			groups[selector.Name] = extracted
+		}
+
+		mapped, err := applyFieldMap(groups, endpoint.FieldMap)
+		if err != nil {
+			return nil, fmt.Errorf("applying field map: %v", err)
+		}
+		return mapped, nil
 	}
 
-	// Extract from endpoint B
-	extractedB, err := extractPath(jsonB, jsonPath)
+	jsonPath := selectors.path()
+	if results != jsonPathAll || jsonPath == "" {
+		extracted, err := extractPath(data, jsonPath)
+		if err != nil {
+			return nil, fmt.Errorf("extracting JSON path: %v", err)
+		}
+
+		mapped, err := applyFieldMap(extracted, endpoint.FieldMap)
+		if err != nil {
+			return nil, fmt.Errorf("applying field map: %v", err)
+		}
+		return mapped, nil
+	}
+
+	nodes, err := extractNodes(data, jsonPath)
 	if err != nil {
-		return nil, nil, fmt.Errorf("Error extracting JSON path from endpoint B: %v", err)
+		return nil, fmt.Errorf("extracting JSON path: %v", err)
+	}
+	for location, node := range nodes {
+		if nodes[location], err = applyFieldMap(node, endpoint.FieldMap); err != nil {
+			return nil, fmt.Errorf("applying field map at %s: %v", location, err)
+		}
 	}
+	return nodes, nil
+}
 
-	return extractedA, extractedB, nil
+// Applies the transforms to both documents
+func transformDocuments(dataA, dataB interface{}, transforms []Transform) (interface{}, interface{}, error) {
+	transformedA, err := applyTransforms(dataA, transforms)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error transforming endpoint A: %v", err)
+	}
+
+	transformedB, err := applyTransforms(dataB, transforms)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error transforming endpoint B: %v", err)
+	}
+
+	return transformedA, transformedB, nil
 }
 
-// Compares data and reports results
-func compareAndReportResults(dataA, dataB interface{}, ignoredKeys []string) {
-	equal, diffs := compareJSON(dataA, dataB, ignoredKeys)
+// Extracts the single result of a JSON path, or all results keyed by their normalized paths
+func extractSelection(data interface{}, jsonPath, results string) (interface{}, error) {
+	if results == jsonPathAll {
+		return extractNodes(data, jsonPath)
+	}
+	return extractPath(data, jsonPath)
+}
 
-	if !equal {
-		fmt.Println("Difference found:")
-		for _, diff := range diffs {
-			fmt.Println(diff)
+// Prints the compared documents as indented JSON, redacting secret values
+func printDocuments(w io.Writer, endpointA, endpointB Endpoint, result comparisonResult) error {
+	for i, doc := range []struct {
+		endpoint Endpoint
+		data     interface{}
+	}{{endpointA, result.documentA}, {endpointB, result.documentB}} {
+		data, err := json.MarshalIndent(doc.data, "", "  ")
+		if err != nil {
+			return err
 		}
+		fmt.Fprintf(w, "Document %c (%s) after extraction and transforms:\n%s\n\n", 'A'+i, doc.endpoint.Name, secrets.redact(string(data)))
+	}
+	return nil
+}
+
+// Reports comparison results and returns the corresponding exit code
+func reportResults(result comparisonResult) int {
+	reported := reportViolations(result.violations)
+	reported = reportAcceptance(result.acceptanceResult, result.selectors) || reported
+	reported = reportNoise(result.noisy) || reported
+
+	if len(result.violations) > 0 {
+		fmt.Println("\nEndpoints violate the JSON Schema.")
+		return exitSchemaViolation
+	}
+
+	if result.failed() {
 		fmt.Println("\nEndpoints contain different configuration.")
-		os.Exit(1)
+		return 1
 	}
 
+	if reported {
+		fmt.Println()
+	}
 	fmt.Println("Endpoints contain identical configuration.")
-	os.Exit(0)
+	return 0
+}
+
+// Prints differences grouped by their acceptance state and reports whether anything was printed
+func reportAcceptance(result acceptanceResult, selectors []string) bool {
+	for _, group := range groupDiffs(result.unaccepted, selectors) {
+		if group.selector == "" {
+			fmt.Println("Difference found:")
+		} else {
+			fmt.Printf("Difference found in %s:\n", group.selector)
+		}
+		for _, diff := range group.diffs {
+			fmt.Println(formatDiff(diff))
+		}
+	}
+
+	if len(result.expired) > 0 {
+		fmt.Println("Expired accepted differences:")
+		for _, m := range result.expired {
+			fmt.Printf("%s\n  Owner: %s (expired %s)\n", formatDiff(m.diff), m.entry.Owner, m.entry.Expires)
+		}
+	}
+
+	if len(result.accepted) > 0 {
+		fmt.Println("Accepted differences:")
+		for _, m := range result.accepted {
+			fmt.Printf("%s\n  Owner: %s (expires %s)\n", formatDiff(m.diff), m.entry.Owner, m.entry.Expires)
+		}
+	}
+
+	if len(result.stale) > 0 {
+		fmt.Println("Stale accepted differences (no longer match):")
+		for _, entry := range result.stale {
+			fmt.Printf("- Path: %s\n  Owner: %s (expires %s)\n", entry.Path, entry.Owner, entry.Expires)
+		}
+	}
+
+	return len(result.unaccepted)+len(result.expired)+len(result.accepted)+len(result.stale) > 0
+}
+
+// Prints non-deterministic paths and reports whether anything was printed
+func reportNoise(noisy []noisyPath) bool {
+	if len(noisy) == 0 {
+		return false
+	}
+
+	fmt.Println("Non-deterministic paths (excluded from comparison):")
+	for _, n := range noisy {
+		fmt.Printf("- Path: %s (%s)\n", n.path, strings.Join(n.endpoints, ", "))
+	}
+	return true
+}
+
+// Prints JSON Schema violations and reports whether anything was printed
+func reportViolations(violations []schemaViolation) bool {
+	if len(violations) == 0 {
+		return false
+	}
+
+	fmt.Println("Schema violations:")
+	for _, v := range violations {
+		fmt.Println(formatViolation(v))
+	}
+	return true
 }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Represents the result of comparing two endpoints
+type comparisonResult struct {
+	acceptanceResult
+	noisy      []noisyPath       // Non-deterministic paths excluded from the comparison
+	violations []schemaViolation // JSON Schema violations of either document
+	selectors  []string          // Names of the JSON path selectors grouping the differences, if any
+
+	documentA, documentB interface{} // Compared documents, after extraction and transforms
+}
+
+// Returns the exit code corresponding to the result
+func (r comparisonResult) exitCode() int {
+	switch {
+	case len(r.violations) > 0:
+		return exitSchemaViolation
+	case r.failed():
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Represents a path whose value changes between samples of the same endpoint
+type noisyPath struct {
+	path      string
+	endpoints []string
+}
+
+// Fetches additional samples of both endpoints and returns the paths that differ between samples
+func detectNoisyPaths(config *Config, endpointA, endpointB Endpoint, dataA, dataB interface{}, opts *compareOptions) ([]noisyPath, error) {
+	endpoints := make(map[string][]string)
+
+	for _, sample := range []struct {
+		endpoint Endpoint
+		data     interface{}
+	}{{endpointA, dataA}, {endpointB, dataB}} {
+		paths, err := sampleNoise(config, sample.endpoint, sample.data, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			endpoints[path] = append(endpoints[path], sample.endpoint.Name)
+		}
+	}
+
+	var noisy []noisyPath
+	for path, names := range endpoints {
+		noisy = append(noisy, noisyPath{path, names})
+	}
+	sort.Slice(noisy, func(i, j int) bool {
+		return noisy[i].path < noisy[j].path
+	})
+	return noisy, nil
+}
+
+// Fetches the remaining samples of an endpoint and returns the paths differing from the first sample
+func sampleNoise(config *Config, endpoint Endpoint, first interface{}, opts *compareOptions) ([]string, error) {
+	timeout := time.Duration(config.GetTimeout()) * time.Second
+	seen := make(map[string]bool)
+	var paths []string
+
+	for i := 1; i < config.GetNoiseSamples(); i++ {
+		sample, err := fetchJSON(endpoint, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("Error fetching sample %d from endpoint %s: %v", i+1, endpoint.Name, err)
+		}
+
+		data, err := extractEndpointData(endpoint, sample, config.GetJSONPathSelectors(), config.GetJSONPathResults())
+		if err != nil {
+			return nil, fmt.Errorf("Error processing sample %d of endpoint %s: %v", i+1, endpoint.Name, err)
+		}
+		if data, err = applyTransforms(data, config.GetTransforms()); err != nil {
+			return nil, fmt.Errorf("Error transforming sample %d of endpoint %s: %v", i+1, endpoint.Name, err)
+		}
+
+		for _, diff := range diffJSON(first, data, opts) {
+			if !seen[diff.path] {
+				seen[diff.path] = true
+				paths = append(paths, diff.path)
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// Removes differences at or below non-deterministic paths
+func filterNoisyDiffs(diffs []diffInfo, noisy []noisyPath) []diffInfo {
+	if len(noisy) == 0 {
+		return diffs
+	}
+
+	var result []diffInfo
+	for _, diff := range diffs {
+		excluded := false
+		for _, n := range noisy {
+			if isUnderPath(diff.path, n.path) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			result = append(result, diff)
+		}
+	}
+	return result
+}
+
+// Reports whether a path equals or is nested below the given parent path.
+// The root path only matches itself, so that a noisy scalar document does not hide every difference.
+func isUnderPath(path, parent string) bool {
+	if path == parent {
+		return true
+	}
+	if parent == "" || !strings.HasPrefix(path, parent) {
+		return false
+	}
+	next := path[len(parent)]
+	return next == '.' || next == '['
+}
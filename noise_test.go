@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCompareEndpointsWithNoiseSamples(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		timeout := 30
+		if r.URL.Path == "/b" {
+			timeout = 60
+		}
+		fmt.Fprintf(w, `{"generatedAt":%d,"cache":{"hits":%d},"timeout":%d}`, n, n, timeout)
+	}))
+	defer server.Close()
+
+	config := &Config{
+		Endpoints: []Endpoint{
+			{Name: "Production", URL: server.URL + "/a"},
+			{Name: "Staging", URL: server.URL + "/b"},
+		},
+		Settings: Settings{NoiseSamples: 3},
+	}
+	setDefaults(config)
+
+	result, err := compareEndpoints(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if calls.Load() != 6 {
+		t.Errorf("Expected 6 requests, got %d", calls.Load())
+	}
+	if len(result.unaccepted) != 1 || result.unaccepted[0].path != "timeout" {
+		t.Errorf("Expected only timeout to differ, got %v", result.unaccepted)
+	}
+	if len(result.noisy) != 2 || result.noisy[0].path != "cache.hits" || result.noisy[1].path != "generatedAt" {
+		t.Errorf("Unexpected noisy paths: %v", result.noisy)
+	}
+	if len(result.noisy) > 0 && len(result.noisy[0].endpoints) != 2 {
+		t.Errorf("Expected noise on both endpoints, got %v", result.noisy[0].endpoints)
+	}
+}
+
+func TestIsUnderPath(t *testing.T) {
+	cases := []struct {
+		path   string
+		parent string
+		want   bool
+	}{
+		{"cache", "cache", true},
+		{"cache.hits", "cache", true},
+		{"items[0].id", "items", true},
+		{"cacheSize", "cache", false},
+		{"other", "cache", false},
+		{"", "", true},
+		{"cache", "", false},
+		{"[0]", "", false},
+	}
+
+	for _, c := range cases {
+		if got := isUnderPath(c.path, c.parent); got != c.want {
+			t.Errorf("isUnderPath(%q, %q) = %v, want %v", c.path, c.parent, got, c.want)
+		}
+	}
+}
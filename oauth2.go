@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tokens are refreshed this long before they expire, or halfway through their lifetime if they are shorter lived
+const tokenExpirySkew = 30 * time.Second
+
+// Represents OAuth2 client credentials authentication
+type OAuth2Config struct {
+	TokenURL     string   `yaml:"tokenURL"`
+	ClientID     string   `yaml:"clientID"`
+	ClientSecret string   `yaml:"clientSecret"`
+	Scopes       []string `yaml:"scopes,omitempty"`
+	Audience     string   `yaml:"audience,omitempty"`
+
+	mu            sync.Mutex // Guards the cached token
+	authorization string     // Cached Authorization header value
+	expiry        time.Time  // Zero if the token does not expire
+}
+
+// Represents a token endpoint response
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// Validates the OAuth2 settings
+func (o *OAuth2Config) validate() error {
+	if o.TokenURL == "" {
+		return errors.New("oauth2 tokenURL is required")
+	}
+	if !isHTTPSource(o.TokenURL) {
+		return errors.New("oauth2 tokenURL must be an http or https URL")
+	}
+	if o.ClientID == "" {
+		return errors.New("oauth2 clientID is required")
+	}
+	return nil
+}
+
+// Returns the Authorization header value, obtaining a new token when none is cached or it expired
+func (o *OAuth2Config) token(timeout time.Duration, forceRefresh bool) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !forceRefresh && o.authorization != "" && (o.expiry.IsZero() || time.Now().Before(o.expiry)) {
+		return o.authorization, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", o.ClientID)
+	form.Set("client_secret", o.ClientSecret)
+	if len(o.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.Scopes, " "))
+	}
+	if o.Audience != "" {
+		form.Set("audience", o.Audience)
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.PostForm(o.TokenURL, form)
+	if err != nil {
+		return "", fmt.Errorf("requesting OAuth2 token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading OAuth2 token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("requesting OAuth2 token: status %d", resp.StatusCode)
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("parsing OAuth2 token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("OAuth2 token response contains no access_token")
+	}
+
+	tokenType := "Bearer"
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		tokenType = token.TokenType
+	}
+	secrets.add(token.AccessToken)
+	o.authorization = tokenType + " " + token.AccessToken
+
+	o.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		o.expiry = time.Now().Add(tokenLifetime(token.ExpiresIn))
+	}
+
+	return o.authorization, nil
+}
+
+// Returns how long a token is used, refreshing it before it expires
+func tokenLifetime(expiresIn int) time.Duration {
+	lifetime := time.Duration(expiresIn) * time.Second
+	return lifetime - min(tokenExpirySkew, lifetime/2)
+}
+
+// Returns the Authorization header value of an endpoint
+func authorization(endpoint Endpoint, timeout time.Duration) (string, error) {
+	if endpoint.OAuth2 != nil {
+		return endpoint.OAuth2.token(timeout, false)
+	}
+	return endpoint.Auth, nil
+}
+
+// Reports whether headers contain a header, whatever the case of its name
+func hasHeader(headers map[string]string, name string) bool {
+	_, ok := headerValue(headers, name)
+	return ok
+}
+
+// Returns the value of a header, whatever the case of its name
+func headerValue(headers map[string]string, name string) (string, bool) {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Sends a request with the endpoint's authentication, refreshing an OAuth2 token once on 401
+func sendWithAuth(endpoint Endpoint, request httpRequest, timeout time.Duration) (*rawResponse, error) {
+	request.headers = withEndpointHeaders(endpoint, request.headers)
+
+	// Explicit Authorization headers take precedence over the endpoint authentication
+	if hasHeader(request.headers, "Authorization") {
+		return sendHTTP(request, timeout)
+	}
+
+	auth, err := authorization(endpoint, timeout)
+	if err != nil {
+		return nil, err
+	}
+	request.auth = auth
+
+	resp, err := sendHTTP(request, timeout)
+	if err != nil || endpoint.OAuth2 == nil || resp.statusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// The token may have been revoked before it expired
+	if request.auth, err = endpoint.OAuth2.token(timeout, true); err != nil {
+		return nil, err
+	}
+	return sendHTTP(request, timeout)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOAuth2TokenCaching(t *testing.T) {
+	var issued atomic.Int64
+	var revoked atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			r.ParseForm()
+			if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("client_id") != "app" ||
+				r.Form.Get("client_secret") != "s3cret" || r.Form.Get("scope") != "read write" || r.Form.Get("audience") != "api" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			n := issued.Add(1)
+			w.Write([]byte(`{"access_token":"token` + string(rune('0'+n)) + `","token_type":"bearer","expires_in":3600}`))
+		case "/config":
+			// The first token is revoked after the first request
+			auth := r.Header.Get("Authorization")
+			if auth == "" || (revoked.Load() && auth == "Bearer token1") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"auth":"` + auth + `"}`))
+		}
+	}))
+	defer server.Close()
+
+	endpoint := Endpoint{
+		Name: "Production",
+		URL:  server.URL + "/config",
+		OAuth2: &OAuth2Config{
+			TokenURL:     server.URL + "/token",
+			ClientID:     "app",
+			ClientSecret: "s3cret",
+			Scopes:       []string{"read", "write"},
+			Audience:     "api",
+		},
+	}
+	if err := endpoint.OAuth2.validate(); err != nil {
+		t.Fatalf("Unexpected validation error: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		data, err := fetchJSON(endpoint, 5*time.Second)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if data.(map[string]interface{})["auth"] != "Bearer token1" {
+			t.Errorf("Unexpected authorization: %v", data)
+		}
+	}
+	if issued.Load() != 1 {
+		t.Errorf("Expected token to be cached, %d tokens issued", issued.Load())
+	}
+
+	// A 401 refreshes the token once
+	revoked.Store(true)
+	data, err := fetchJSON(endpoint, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data.(map[string]interface{})["auth"] != "Bearer token2" || issued.Load() != 2 {
+		t.Errorf("Expected refreshed token, got %v (%d tokens issued)", data, issued.Load())
+	}
+}
+
+func TestOAuth2ShortLivedToken(t *testing.T) {
+	var issued atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			issued.Add(1)
+			w.Write([]byte(`{"access_token":"short","expires_in":10}`))
+		case "/config":
+			w.Write([]byte(`{"auth":"` + r.Header.Get("Authorization") + `"}`))
+		}
+	}))
+	defer server.Close()
+
+	endpoint := Endpoint{
+		Name:   "Production",
+		URL:    server.URL + "/config",
+		OAuth2: &OAuth2Config{TokenURL: server.URL + "/token", ClientID: "app", ClientSecret: "s3cret"},
+	}
+
+	// Tokens living less than twice the skew are still reused
+	for i := 0; i < 3; i++ {
+		if _, err := fetchJSON(endpoint, 5*time.Second); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if issued.Load() != 1 {
+		t.Errorf("Expected token to be cached, %d tokens issued", issued.Load())
+	}
+
+	testCases := []struct {
+		expiresIn int
+		want      time.Duration
+	}{
+		{3600, 3600*time.Second - tokenExpirySkew},
+		{60, 30 * time.Second},
+		{10, 5 * time.Second},
+		{1, 500 * time.Millisecond},
+	}
+	for _, tc := range testCases {
+		if got := tokenLifetime(tc.expiresIn); got != tc.want {
+			t.Errorf("tokenLifetime(%d) = %v, want %v", tc.expiresIn, got, tc.want)
+		}
+	}
+}
+
+func TestExplicitAuthorizationHeader(t *testing.T) {
+	var issued atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			issued.Add(1)
+			w.Write([]byte(`{"access_token":"injected"}`))
+		case "/config":
+			w.Write([]byte(`{"auth":"` + strings.Join(r.Header.Values("Authorization"), ",") + `"}`))
+		}
+	}))
+	defer server.Close()
+
+	// Header names are case-insensitive
+	endpoint := Endpoint{
+		Name:    "Production",
+		URL:     server.URL + "/config",
+		Headers: map[string]string{"authorization": "Bearer mine"},
+		OAuth2:  &OAuth2Config{TokenURL: server.URL + "/token", ClientID: "app", ClientSecret: "s3cret"},
+	}
+	data, err := fetchJSON(endpoint, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data.(map[string]interface{})["auth"] != "Bearer mine" || issued.Load() != 0 {
+		t.Errorf("Expected only the explicit header, got %v (%d tokens issued)", data, issued.Load())
+	}
+}
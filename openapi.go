@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Prefix of references to reusable parameters
+const openAPIParameterRef = "#/components/parameters/"
+
+// Represents the settings of OpenAPI-driven discovery
+type OpenAPISettings struct {
+	File     string            `yaml:"file"`               // OpenAPI 3 document in YAML or JSON
+	Examples map[string]string `yaml:"examples,omitempty"` // Parameter values by name or operationId.name
+}
+
+// Represents an operation that could not be exercised
+type skippedOperation struct {
+	operation string
+	reason    string
+}
+
+// Represents the subset of an OpenAPI 3 document used for discovery
+type openAPIDocument struct {
+	OpenAPI    string                     `yaml:"openapi"`
+	Paths      map[string]openAPIPathItem `yaml:"paths"`
+	Components struct {
+		Parameters map[string]openAPIParameter `yaml:"parameters"`
+	} `yaml:"components"`
+}
+
+// Represents an OpenAPI path item
+type openAPIPathItem struct {
+	Get        *openAPIOperation  `yaml:"get"`
+	Parameters []openAPIParameter `yaml:"parameters"`
+}
+
+// Represents an OpenAPI operation
+type openAPIOperation struct {
+	OperationID string             `yaml:"operationId"`
+	Parameters  []openAPIParameter `yaml:"parameters"`
+}
+
+// Represents an OpenAPI parameter or a reference to one
+type openAPIParameter struct {
+	Ref      string `yaml:"$ref"`
+	Name     string `yaml:"name"`
+	In       string `yaml:"in"`
+	Required bool   `yaml:"required"`
+}
+
+// Loads an OpenAPI document and converts its GET operations into requests
+func loadOpenAPIRequests(path string, examples map[string]string) ([]replayRequest, []skippedOperation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// YAML is a superset of JSON, so both formats are accepted
+	var doc openAPIDocument
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, nil, errors.New("unsupported OpenAPI version: " + doc.OpenAPI)
+	}
+
+	// Process paths in a stable order
+	var paths []string
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var requests []replayRequest
+	var skipped []skippedOperation
+	for _, path := range paths {
+		item := doc.Paths[path]
+		if item.Get == nil {
+			continue
+		}
+
+		request, err := doc.buildRequest(path, item, examples)
+		if err != nil {
+			skipped = append(skipped, skippedOperation{operationName(path, item.Get), err.Error()})
+			continue
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, skipped, nil
+}
+
+// Builds a request for the GET operation of a path, filling parameters from the examples
+func (doc *openAPIDocument) buildRequest(path string, item openAPIPathItem, examples map[string]string) (replayRequest, error) {
+	params, err := doc.operationParameters(item)
+	if err != nil {
+		return replayRequest{}, err
+	}
+
+	request := replayRequest{Method: http.MethodGet, Path: path}
+	query := url.Values{}
+	for _, param := range params {
+		value, ok := lookupExample(examples, item.Get.OperationID, param.Name)
+		if !ok {
+			// Path parameters are always required
+			if param.Required || param.In == "path" {
+				return replayRequest{}, fmt.Errorf("no example for %s parameter %s", param.In, param.Name)
+			}
+			continue
+		}
+
+		switch param.In {
+		case "path":
+			request.Path = strings.ReplaceAll(request.Path, "{"+param.Name+"}", url.PathEscape(value))
+		case "query":
+			query.Add(param.Name, value)
+		case "header":
+			if request.Headers == nil {
+				request.Headers = make(map[string]string)
+			}
+			request.Headers[param.Name] = value
+		default:
+			return replayRequest{}, fmt.Errorf("unsupported %s parameter %s", param.In, param.Name)
+		}
+	}
+	request.Query = query.Encode()
+
+	return request, nil
+}
+
+// Returns the parameters of the GET operation, with operation parameters overriding path item parameters
+func (doc *openAPIDocument) operationParameters(item openAPIPathItem) ([]openAPIParameter, error) {
+	var params []openAPIParameter
+	index := make(map[string]int)
+
+	for _, param := range append(append([]openAPIParameter{}, item.Parameters...), item.Get.Parameters...) {
+		resolved, err := doc.resolveParameter(param)
+		if err != nil {
+			return nil, err
+		}
+
+		key := resolved.In + ":" + resolved.Name
+		if i, ok := index[key]; ok {
+			params[i] = resolved
+			continue
+		}
+		index[key] = len(params)
+		params = append(params, resolved)
+	}
+
+	return params, nil
+}
+
+// Resolves a reference to a reusable parameter
+func (doc *openAPIDocument) resolveParameter(param openAPIParameter) (openAPIParameter, error) {
+	if param.Ref == "" {
+		return param, nil
+	}
+	if !strings.HasPrefix(param.Ref, openAPIParameterRef) {
+		return param, errors.New("unsupported parameter reference: " + param.Ref)
+	}
+
+	resolved, ok := doc.Components.Parameters[strings.TrimPrefix(param.Ref, openAPIParameterRef)]
+	if !ok {
+		return param, errors.New("unresolved parameter reference: " + param.Ref)
+	}
+	return resolved, nil
+}
+
+// Looks up a parameter value, preferring operation-specific examples
+func lookupExample(examples map[string]string, operationID, name string) (string, bool) {
+	if operationID != "" {
+		if value, ok := examples[operationID+"."+name]; ok {
+			return value, true
+		}
+	}
+	value, ok := examples[name]
+	return value, ok
+}
+
+// Returns a description of an operation for reports
+func operationName(path string, op *openAPIOperation) string {
+	name := "GET " + path
+	if op.OperationID != "" {
+		name += " (" + op.OperationID + ")"
+	}
+	return name
+}
+
+// Prints the operations that could not be exercised
+func reportSkippedOperations(skipped []skippedOperation) {
+	if len(skipped) == 0 {
+		return
+	}
+
+	fmt.Println("\nOperations not exercised:")
+	for _, op := range skipped {
+		fmt.Printf("- %s: %s\n", op.operation, op.reason)
+	}
+}
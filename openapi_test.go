@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadOpenAPIRequests(t *testing.T) {
+	spec := `
+openapi: 3.0.3
+info:
+  title: Example
+  version: "1"
+paths:
+  /health:
+    get:
+      operationId: health
+  /users:
+    get:
+      operationId: listUsers
+      parameters:
+        - name: limit
+          in: query
+        - $ref: "#/components/parameters/Tenant"
+    post:
+      operationId: createUser
+  /users/{userId}:
+    parameters:
+      - name: userId
+        in: path
+        required: true
+    get:
+      operationId: getUser
+  /orders/{orderId}:
+    get:
+      operationId: getOrder
+      parameters:
+        - name: orderId
+          in: path
+          required: true
+  /reports:
+    get:
+      parameters:
+        - name: session
+          in: cookie
+          required: true
+components:
+  parameters:
+    Tenant:
+      name: X-Tenant
+      in: header
+      required: true
+`
+	path := filepath.Join(t.TempDir(), "openapi.yaml")
+	if err := os.WriteFile(path, []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	examples := map[string]string{
+		"userId":         "1",
+		"getUser.userId": "a b",
+		"limit":          "10",
+		"X-Tenant":       "acme",
+		"session":        "abc",
+	}
+	requests, skipped, err := loadOpenAPIRequests(path, examples)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []string{"GET /health", "GET /users?limit=10", "GET /users/a%20b"}
+	var got []string
+	for _, request := range requests {
+		got = append(got, request.String())
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected requests %v, got %v (skipped: %v)", want, got, skipped)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Request %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+	if requests[1].Headers["X-Tenant"] != "acme" {
+		t.Errorf("Expected header parameter, got %v", requests[1].Headers)
+	}
+
+	// Missing examples and cookie parameters cannot be exercised
+	if len(skipped) != 2 || skipped[0].operation != "GET /orders/{orderId} (getOrder)" || skipped[1].operation != "GET /reports" {
+		t.Errorf("Unexpected skipped operations: %v", skipped)
+	}
+}
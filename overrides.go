@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Represents a flag that can be repeated, collecting every value
+type stringList []string
+
+// Returns the values separated by commas
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Appends a value
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// Represents command line flags overriding the configuration
+type overrides struct {
+	ignoredKeys stringList // Added to Settings.IgnoredKeys
+	headers     stringList // "Name: value" headers sent to both endpoints
+	jsonPath    string
+	results     string // How the results of the JSONPath are compared
+	timeout     int
+	endpoints   string // Comma separated names of the endpoints to compare
+}
+
+// Registers the override flags on a flag set
+func (o *overrides) register(fs *flag.FlagSet) {
+	fs.Var(&o.ignoredKeys, "ignore", "key to ignore during comparison (repeatable)")
+	fs.Var(&o.headers, "header", "header sent to both endpoints, e.g. 'X-Tenant: 1' (repeatable)")
+	fs.StringVar(&o.jsonPath, "jsonpath", "", "JSONPath expression to extract before comparing")
+	fs.StringVar(&o.results, "jsonpath-results", "", "compare a single JSONPath result (single) or all results by location (all)")
+	fs.IntVar(&o.timeout, "timeout", 0, "HTTP timeout in seconds")
+	fs.StringVar(&o.endpoints, "endpoints", "", "comma separated names of the two endpoints to compare, e.g. prod,stg")
+}
+
+// Applies the overrides to a loaded configuration
+func (o *overrides) apply(config *Config) error {
+	if o.timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+	if o.timeout > 0 {
+		config.Settings.Timeout = o.timeout
+	}
+
+	if o.jsonPath != "" {
+		config.Settings.JSONPath = singleSelector(o.jsonPath)
+	}
+	switch o.results {
+	case "":
+	case jsonPathSingle, jsonPathAll:
+		config.Settings.JSONPathResults = o.results
+	default:
+		return fmt.Errorf("unknown jsonpath-results: %s (expected %s or %s)", o.results, jsonPathSingle, jsonPathAll)
+	}
+
+	config.Settings.IgnoredKeys = append(config.Settings.IgnoredKeys, o.ignoredKeys...)
+
+	if o.endpoints != "" {
+		selected, err := selectEndpoints(config.Endpoints, strings.Split(o.endpoints, ","))
+		if err != nil {
+			return err
+		}
+		config.Endpoints = selected
+	}
+	if err := config.checkNodeSelectors(); err != nil {
+		return err
+	}
+
+	headers, err := parseHeaders(o.headers)
+	if err != nil {
+		return err
+	}
+	for i := range config.Endpoints {
+		// Headers only apply to HTTP sources
+		if !isHTTPSource(config.Endpoints[i].URL) {
+			continue
+		}
+		if len(headers) > 0 && config.Endpoints[i].Headers == nil {
+			config.Endpoints[i].Headers = make(map[string]string)
+		}
+		for name, value := range headers {
+			config.Endpoints[i].Headers[name] = value
+		}
+	}
+
+	return nil
+}
+
+// Returns the named endpoints in the given order
+func selectEndpoints(endpoints []Endpoint, names []string) ([]Endpoint, error) {
+	if len(names) != 2 {
+		return nil, errors.New("endpoints must name exactly two endpoints, e.g. prod,stg")
+	}
+
+	var selected []Endpoint
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, endpoint := range endpoints {
+			if endpoint.Name == name {
+				selected = append(selected, endpoint)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown endpoint: %s", name)
+		}
+	}
+	if selected[0].Name == selected[1].Name {
+		return nil, errors.New("endpoints must name two different endpoints")
+	}
+
+	return selected, nil
+}
+
+// Parses "Name: value" headers
+func parseHeaders(values []string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, value := range values {
+		name, headerValue, ok := strings.Cut(value, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q, expected 'Name: value'", value)
+		}
+		headers[name] = strings.TrimSpace(headerValue)
+	}
+	return headers, nil
+}
+
+// Creates a configuration comparing two sources given on the command line
+func newAdHocConfig(sourceA, sourceB string) (*Config, error) {
+	config := &Config{
+		Endpoints: []Endpoint{
+			{Name: "A", URL: sourceA},
+			{Name: "B", URL: sourceB},
+		},
+	}
+
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
+	setDefaults(config)
+
+	// Resolve file sources relative to the working directory
+	for i := range config.Endpoints {
+		config.Endpoints[i].URL = resolveSource(".", config.Endpoints[i].URL)
+	}
+
+	return config, nil
+}
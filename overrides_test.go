@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOverridesApply(t *testing.T) {
+	newConfig := func() *Config {
+		return &Config{
+			Endpoints: []Endpoint{
+				{Name: "prod", URL: "https://api.example.com/config"},
+				{Name: "dev", URL: "file://dev.json"},
+				{Name: "stg", URL: "https://staging.example.com/config"},
+			},
+			Settings: Settings{Timeout: 30, IgnoredKeys: []string{"id"}, JSONPath: singleSelector("$.config")},
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		args      []string
+		check     func(t *testing.T, config *Config)
+		wantError bool
+	}{
+		{
+			name: "no flags keep the configuration",
+			check: func(t *testing.T, config *Config) {
+				if !reflect.DeepEqual(config, newConfig()) {
+					t.Errorf("Unexpected configuration: %+v", config)
+				}
+			},
+		},
+		{
+			name: "settings overrides",
+			args: []string{"--timeout", "5", "--jsonpath", "$.features", "--jsonpath-results", "all", "--ignore", "timestamp", "--ignore", "etag"},
+			check: func(t *testing.T, config *Config) {
+				if config.GetTimeout() != 5 || config.GetJSONPath() != "$.features" || config.GetJSONPathResults() != jsonPathAll {
+					t.Errorf("Unexpected settings: %+v", config.Settings)
+				}
+				if !reflect.DeepEqual(config.GetIgnoredKeys(), []string{"id", "timestamp", "etag"}) {
+					t.Errorf("Unexpected ignored keys: %v", config.GetIgnoredKeys())
+				}
+			},
+		},
+		{
+			name: "endpoint selection",
+			args: []string{"--endpoints", "stg,prod"},
+			check: func(t *testing.T, config *Config) {
+				a, b := config.GetDefaultEndpoints()
+				if a.Name != "stg" || b.Name != "prod" {
+					t.Errorf("Unexpected endpoints: %s, %s", a.Name, b.Name)
+				}
+			},
+		},
+		{
+			name: "headers for http endpoints",
+			args: []string{"--header", "X-Tenant: 1", "--header", "Accept-Language:en"},
+			check: func(t *testing.T, config *Config) {
+				want := map[string]string{"X-Tenant": "1", "Accept-Language": "en"}
+				if !reflect.DeepEqual(config.Endpoints[0].Headers, want) {
+					t.Errorf("Unexpected headers: %v", config.Endpoints[0].Headers)
+				}
+				if config.Endpoints[1].Headers != nil {
+					t.Errorf("Headers set on file source: %v", config.Endpoints[1].Headers)
+				}
+			},
+		},
+		{name: "unknown endpoint", args: []string{"--endpoints", "prod,qa"}, wantError: true},
+		{name: "single endpoint", args: []string{"--endpoints", "prod"}, wantError: true},
+		{name: "same endpoint twice", args: []string{"--endpoints", "prod,prod"}, wantError: true},
+		{name: "invalid header", args: []string{"--header", "X-Tenant"}, wantError: true},
+		{name: "unknown jsonpath results", args: []string{"--jsonpath-results", "first"}, wantError: true},
+		{name: "negative timeout", args: []string{"--timeout", "-1"}, wantError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			var o overrides
+			o.register(fs)
+			if err := fs.Parse(tc.args); err != nil {
+				t.Fatal(err)
+			}
+
+			config := newConfig()
+			err := o.apply(config)
+			if tc.wantError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			tc.check(t, config)
+		})
+	}
+}
+
+func TestEndpointHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tenant":"` + r.Header.Get("X-Tenant") + `"}`))
+	}))
+	defer server.Close()
+
+	config, err := newAdHocConfig(server.URL, server.URL+"/other")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config.Endpoints[0].Headers = map[string]string{"X-Tenant": "1"}
+
+	data, err := fetchJSON(config.Endpoints[0], time.Duration(config.GetTimeout())*time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data.(map[string]interface{})["tenant"] != "1" {
+		t.Errorf("Header not sent: %v", data)
+	}
+
+	if _, err := newAdHocConfig(server.URL, "ftp://example.com"); err == nil {
+		t.Error("Expected error for invalid source")
+	}
+}
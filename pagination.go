@@ -0,0 +1,377 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/theory/jsonpath"
+)
+
+// Pagination types
+const (
+	paginationLink   = "link"   // Follow RFC 8288 Link: rel="next" headers
+	paginationCursor = "cursor" // Read the next cursor from the response body
+	paginationPage   = "page"   // Increment a page number parameter
+	paginationOffset = "offset" // Increment an offset parameter by the number of items
+)
+
+// Default pagination settings
+const (
+	defaultMaxPages    = 100
+	defaultCursorParam = "cursor"
+	defaultPageParam   = "page"
+	defaultOffsetParam = "offset"
+	defaultLimitParam  = "limit"
+)
+
+// Represents how to fetch all pages of a collection endpoint
+type Pagination struct {
+	Type        string `yaml:"type"`                  // link, cursor, page or offset
+	ItemsPath   string `yaml:"itemsPath,omitempty"`   // JSONPath of the items array in each page (default: the whole body)
+	CursorPath  string `yaml:"cursorPath,omitempty"`  // JSONPath of the next cursor (cursor type)
+	CursorParam string `yaml:"cursorParam,omitempty"` // Query parameter receiving the cursor (cursor type)
+	PageParam   string `yaml:"pageParam,omitempty"`   // Query parameter receiving the page number (page type)
+	StartPage   int    `yaml:"startPage,omitempty"`   // First page number (page type, default: 1)
+	OffsetParam string `yaml:"offsetParam,omitempty"` // Query parameter receiving the offset (offset type)
+	LimitParam  string `yaml:"limitParam,omitempty"`  // Query parameter receiving the page size (offset type)
+	Limit       int    `yaml:"limit,omitempty"`       // Page size (offset type)
+	TotalPath   string `yaml:"totalPath,omitempty"`   // JSONPath of the total number of items (offset type, optional)
+	MaxPages    int    `yaml:"maxPages,omitempty"`    // Maximum number of pages fetched (default: 100)
+}
+
+// Validates the pagination settings
+func (p *Pagination) validate() error {
+	switch p.Type {
+	case paginationLink, paginationPage:
+	case paginationCursor:
+		if p.CursorPath == "" {
+			return errors.New("cursorPath is required for cursor pagination")
+		}
+		if _, err := jsonpath.Parse(p.CursorPath); err != nil {
+			return fmt.Errorf("invalid cursorPath: %w", err)
+		}
+	case paginationOffset:
+		if p.Limit <= 0 {
+			return errors.New("limit is required for offset pagination")
+		}
+	default:
+		return errors.New("unknown pagination type: " + p.Type)
+	}
+
+	if p.ItemsPath != "" {
+		if _, err := jsonpath.Parse(p.ItemsPath); err != nil {
+			return fmt.Errorf("invalid itemsPath: %w", err)
+		}
+	}
+	if p.TotalPath != "" {
+		if p.Type != paginationOffset {
+			return errors.New("totalPath is only used by offset pagination")
+		}
+		if _, err := jsonpath.Parse(p.TotalPath); err != nil {
+			return fmt.Errorf("invalid totalPath: %w", err)
+		}
+	}
+	if p.MaxPages < 0 {
+		return errors.New("maxPages must not be negative")
+	}
+	return nil
+}
+
+// Sets default pagination values
+func (p *Pagination) setDefaults() {
+	if p.MaxPages == 0 {
+		p.MaxPages = defaultMaxPages
+	}
+	if p.CursorParam == "" {
+		p.CursorParam = defaultCursorParam
+	}
+	if p.PageParam == "" {
+		p.PageParam = defaultPageParam
+	}
+	if p.StartPage == 0 {
+		p.StartPage = 1
+	}
+	if p.OffsetParam == "" {
+		p.OffsetParam = defaultOffsetParam
+	}
+	if p.LimitParam == "" {
+		p.LimitParam = defaultLimitParam
+	}
+}
+
+// Fetches all pages of an endpoint and merges their items into one JSON array
+func fetchPaginated(endpoint Endpoint, timeout time.Duration) (*rawResponse, error) {
+	p := endpoint.Pagination
+	var first *rawResponse
+	items := []interface{}{}
+
+	target := endpoint.URL
+	page, offset := p.StartPage, 0
+	more := false // Whether the last fetched page points to a next page
+pages:
+	for n := 0; n < p.MaxPages; n++ {
+		more = false
+		switch p.Type {
+		case paginationPage:
+			target = withQueryParams(endpoint.URL, map[string]string{p.PageParam: strconv.Itoa(page)})
+		case paginationOffset:
+			target = withQueryParams(endpoint.URL, map[string]string{
+				p.OffsetParam: strconv.Itoa(offset),
+				p.LimitParam:  strconv.Itoa(p.Limit),
+			})
+		}
+
+		resp, err := fetchHTTP(endpoint, target, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", n+1, err)
+		}
+		// An error page would otherwise be merged in as data, or fail later as a misleading parse error
+		if resp.statusCode < 200 || resp.statusCode > 299 {
+			return nil, fmt.Errorf("page %d: HTTP %d", n+1, resp.statusCode)
+		}
+		if first == nil {
+			first = resp
+		}
+
+		body, err := parseJSON(resp.body)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", n+1, err)
+		}
+		pageItems, err := extractItems(body, p.ItemsPath)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", n+1, err)
+		}
+		items = append(items, pageItems...)
+
+		// Determine the next page
+		switch p.Type {
+		case paginationLink:
+			next := nextLink(resp.header.Values("Link"))
+			if next == "" {
+				break pages
+			}
+			target = resolveURL(target, next)
+		case paginationCursor:
+			cursor := extractCursor(body, p.CursorPath)
+			if cursor == "" {
+				break pages
+			}
+			// Cursors may be complete URLs or opaque tokens
+			if strings.HasPrefix(cursor, "/") || isHTTPSource(cursor) {
+				target = resolveURL(target, cursor)
+			} else {
+				target = withQueryParams(endpoint.URL, map[string]string{p.CursorParam: cursor})
+			}
+		case paginationPage:
+			if len(pageItems) == 0 {
+				break pages
+			}
+			page++
+		case paginationOffset:
+			// Servers may cap the page size below the limit, so only an empty page or the total ends the collection
+			if len(pageItems) == 0 {
+				break pages
+			}
+			offset += len(pageItems)
+			if total, ok := extractTotal(body, p.TotalPath); ok && offset >= total {
+				break pages
+			}
+		}
+		more = true
+	}
+
+	// A collection cut at the same limit on both endpoints would otherwise compare as identical
+	if more {
+		return nil, fmt.Errorf("more pages remain after maxPages (%d)", p.MaxPages)
+	}
+
+	merged, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	return &rawResponse{
+		body:        merged,
+		statusCode:  first.statusCode,
+		contentType: first.contentType,
+		header:      first.header,
+	}, nil
+}
+
+// Extracts the items array of a page
+func extractItems(body interface{}, itemsPath string) ([]interface{}, error) {
+	items := body
+	if itemsPath != "" {
+		var err error
+		if items, err = extractPath(body, itemsPath); err != nil {
+			return nil, err
+		}
+	}
+
+	array, ok := items.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("items are not an array: %s", itemsPath)
+	}
+	return array, nil
+}
+
+// Extracts the next cursor, returning an empty string when there are no more pages
+func extractCursor(body interface{}, cursorPath string) string {
+	value, err := extractPath(body, cursorPath)
+	if err != nil || value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return formatValue(value)
+}
+
+// Extracts the total number of items, reporting whether the page has one
+func extractTotal(body interface{}, totalPath string) (int, bool) {
+	if totalPath == "" {
+		return 0, false
+	}
+	value, err := extractPath(body, totalPath)
+	if err != nil {
+		return 0, false
+	}
+	switch total := value.(type) {
+	case float64:
+		return int(total), true
+	case string:
+		n, err := strconv.Atoi(total)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// Returns the target of the rel="next" link in Link headers
+func nextLink(headers []string) string {
+	for _, header := range headers {
+		for _, link := range parseLinkHeader(header) {
+			// The rel parameter may contain several space-separated relation types
+			for _, rel := range strings.Fields(link.params["rel"]) {
+				if strings.EqualFold(rel, "next") {
+					return link.target
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// Represents a link of an RFC 8288 Link header
+type headerLink struct {
+	target string
+	params map[string]string // Lowercase parameter names to unquoted values
+}
+
+// Parses the links of a Link header. The <uri> part is read first, so that commas
+// and semicolons inside URIs or quoted parameter values do not split links.
+func parseLinkHeader(header string) []headerLink {
+	var links []headerLink
+	rest := header
+	for {
+		rest = strings.TrimLeft(rest, " \t,")
+		if !strings.HasPrefix(rest, "<") {
+			return links
+		}
+		end := strings.IndexByte(rest, '>')
+		if end < 0 {
+			return links
+		}
+		link := headerLink{target: strings.TrimSpace(rest[1:end]), params: make(map[string]string)}
+		rest = rest[end+1:]
+
+		// Parameters run until the next comma outside a quoted value
+		for {
+			rest = strings.TrimLeft(rest, " \t")
+			if !strings.HasPrefix(rest, ";") {
+				break
+			}
+			var name, value string
+			name, value, rest = parseLinkParam(rest[1:])
+			if name != "" {
+				link.params[strings.ToLower(name)] = value
+			}
+		}
+		links = append(links, link)
+
+		// Skip anything up to the next link
+		if next := strings.IndexByte(rest, ','); next >= 0 {
+			rest = rest[next:]
+		} else {
+			rest = ""
+		}
+	}
+}
+
+// Parses a name=value link parameter whose value may be quoted, returning the rest of the header
+func parseLinkParam(s string) (name, value, rest string) {
+	s = strings.TrimLeft(s, " \t")
+	end := strings.IndexAny(s, "=;,")
+	if end < 0 {
+		return strings.TrimSpace(s), "", ""
+	}
+	name = strings.TrimSpace(s[:end])
+	if s[end] != '=' {
+		return name, "", s[end:]
+	}
+
+	s = strings.TrimLeft(s[end+1:], " \t")
+	if !strings.HasPrefix(s, `"`) {
+		end := strings.IndexAny(s, ";,")
+		if end < 0 {
+			return name, strings.TrimSpace(s), ""
+		}
+		return name, strings.TrimSpace(s[:end]), s[end:]
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return name, b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return name, b.String(), ""
+}
+
+// Resolves a possibly relative reference against a base URL
+func resolveURL(base, ref string) string {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
+
+// Returns the URL with the given query parameters set
+func withQueryParams(rawURL string, params map[string]string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	for name, value := range params {
+		query.Set(name, value)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
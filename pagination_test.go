@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchPaginated(t *testing.T) {
+	// Five items served two per page
+	allItems := []string{"a", "b", "c", "d", "e"}
+	pageOf := func(start int) []string {
+		if start >= len(allItems) {
+			return nil
+		}
+		end := start + 2
+		if end > len(allItems) {
+			end = len(allItems)
+		}
+		return allItems[start:end]
+	}
+	itemsJSON := func(items []string) string {
+		result := "["
+		for i, item := range items {
+			if i > 0 {
+				result += ","
+			}
+			result += strconv.Quote(item)
+		}
+		return result + "]"
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch r.URL.Path {
+		case "/link":
+			start, _ := strconv.Atoi(query.Get("start"))
+			if start+2 < len(allItems) {
+				w.Header().Set("Link", fmt.Sprintf(`</link?start=%d>; rel="next", </link?start=0>; rel="first"`, start+2))
+			}
+			w.Write([]byte(itemsJSON(pageOf(start))))
+		case "/cursor":
+			start, _ := strconv.Atoi(query.Get("cursor"))
+			next := "null"
+			if start+2 < len(allItems) {
+				next = strconv.Quote(strconv.Itoa(start + 2))
+			}
+			fmt.Fprintf(w, `{"data":{"items":%s},"next":%s}`, itemsJSON(pageOf(start)), next)
+		case "/page":
+			page, _ := strconv.Atoi(query.Get("p"))
+			fmt.Fprintf(w, `{"items":%s}`, itemsJSON(pageOf((page-1)*2)))
+		case "/offset":
+			// The page size is capped at 2, whatever the limit
+			offset, _ := strconv.Atoi(query.Get("offset"))
+			if offset >= len(allItems) && query.Get("strict") != "" {
+				w.Write([]byte("no more items"))
+				return
+			}
+			fmt.Fprintf(w, `{"items":%s,"total":%d}`, itemsJSON(pageOf(offset)), len(allItems))
+		}
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name       string
+		path       string
+		pagination Pagination
+		want       []interface{}
+	}{
+		{
+			name:       "link header",
+			path:       "/link",
+			pagination: Pagination{Type: paginationLink},
+			want:       []interface{}{"a", "b", "c", "d", "e"},
+		},
+		{
+			name:       "cursor in body",
+			path:       "/cursor",
+			pagination: Pagination{Type: paginationCursor, ItemsPath: "$.data.items", CursorPath: "$.next"},
+			want:       []interface{}{"a", "b", "c", "d", "e"},
+		},
+		{
+			name:       "page parameter",
+			path:       "/page",
+			pagination: Pagination{Type: paginationPage, ItemsPath: "$.items", PageParam: "p"},
+			want:       []interface{}{"a", "b", "c", "d", "e"},
+		},
+		{
+			name:       "offset parameter",
+			path:       "/offset",
+			pagination: Pagination{Type: paginationOffset, ItemsPath: "$.items", Limit: 2},
+			want:       []interface{}{"a", "b", "c", "d", "e"},
+		},
+		{
+			name:       "offset with capped page size",
+			path:       "/offset",
+			pagination: Pagination{Type: paginationOffset, ItemsPath: "$.items", Limit: 10},
+			want:       []interface{}{"a", "b", "c", "d", "e"},
+		},
+		{
+			name:       "offset with total",
+			path:       "/offset?strict=1",
+			pagination: Pagination{Type: paginationOffset, ItemsPath: "$.items", Limit: 10, TotalPath: "$.total"},
+			want:       []interface{}{"a", "b", "c", "d", "e"},
+		},
+		{
+			name:       "page limit",
+			path:       "/link",
+			pagination: Pagination{Type: paginationLink, MaxPages: 3},
+			want:       []interface{}{"a", "b", "c", "d", "e"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.pagination.validate(); err != nil {
+				t.Fatalf("Unexpected validation error: %v", err)
+			}
+			tc.pagination.setDefaults()
+
+			endpoint := Endpoint{Name: tc.name, URL: server.URL + tc.path, Pagination: &tc.pagination}
+			data, err := fetchJSON(endpoint, 5*time.Second)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(data, tc.want) {
+				t.Errorf("Got %v, want %v", data, tc.want)
+			}
+		})
+	}
+}
+
+func TestFetchPaginatedErrorPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			// An error page that is itself an array
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`["unavailable"]`))
+			return
+		}
+		w.Write([]byte(`["a", "b"]`))
+	}))
+	defer server.Close()
+
+	pagination := &Pagination{Type: paginationPage}
+	pagination.setDefaults()
+	endpoint := Endpoint{Name: "failing", URL: server.URL, Pagination: pagination}
+	if _, err := fetchJSON(endpoint, 5*time.Second); err == nil || !strings.Contains(err.Error(), "page 2: HTTP 503") {
+		t.Errorf("Expected error for the failing page, got %v", err)
+	}
+}
+
+func TestFetchPaginatedMaxPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items": ["a"], "next": "more"}`))
+	}))
+	defer server.Close()
+
+	// The collection is not silently truncated
+	pagination := &Pagination{Type: paginationCursor, ItemsPath: "$.items", CursorPath: "$.next", MaxPages: 2}
+	pagination.setDefaults()
+	endpoint := Endpoint{Name: "endless", URL: server.URL, Pagination: pagination}
+	if _, err := fetchJSON(endpoint, 5*time.Second); err == nil || !strings.Contains(err.Error(), "more pages remain after maxPages (2)") {
+		t.Errorf("Expected error for remaining pages, got %v", err)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	cases := []struct {
+		headers []string
+		want    string
+	}{
+		{[]string{`<https://api.example.com/items?page=2>; rel="next"`}, "https://api.example.com/items?page=2"},
+		{[]string{`<https://a/1>; rel="prev", <https://a/3>; rel="next last"`}, "https://a/3"},
+		{[]string{`<https://a/1>; rel="prev"`, `<https://a/3>; rel=next`}, "https://a/3"},
+		{[]string{`<https://a/1>; rel="last"`}, ""},
+		{[]string{`<https://a/items?ids=1,2;v=3>; title="a, b; c"; rel="next"`}, "https://a/items?ids=1,2;v=3"},
+		{[]string{`<https://a/1>; title="say \"next\", rel=next", <https://a/2>; rel="next"`}, "https://a/2"},
+		{nil, ""},
+	}
+
+	for _, c := range cases {
+		if got := nextLink(c.headers); got != c.want {
+			t.Errorf("nextLink(%v) = %q, want %q", c.headers, got, c.want)
+		}
+	}
+}
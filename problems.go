@@ -0,0 +1,317 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Matches the line number prefix of YAML errors
+var yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// Matches the value quoted in YAML type errors
+var yamlErrorValue = regexp.MustCompile("`([^`]*)`")
+
+// Matches the segments of a configuration path, e.g. endpoints[0].url
+var configPathSegment = regexp.MustCompile(`([^.\[\]]+)|\[(\d+)\]`)
+
+// Represents a problem found in a configuration
+type configProblem struct {
+	file    string // File the problem is located in, if it differs from the configuration file
+	path    string // Location in the configuration, e.g. endpoints[0].url
+	line    int    // Position in the configuration file, 0 if unknown
+	column  int
+	message string
+}
+
+// Represents the problems found in a configuration
+type configProblems []configProblem
+
+// Adds a problem at a configuration path
+func (p *configProblems) add(path, format string, args ...interface{}) {
+	*p = append(*p, configProblem{path: path, message: fmt.Sprintf(format, args...)})
+}
+
+// Returns an error reporting all problems, or nil if there are none
+func (p configProblems) err() error {
+	if len(p) == 0 {
+		return nil
+	}
+	return &configError{problems: p}
+}
+
+// Represents an invalid configuration
+type configError struct {
+	file     string // Configuration file, empty for configurations built in memory
+	problems configProblems
+}
+
+// Formats one problem per line, prefixed with file:line:column when known
+func (e *configError) Error() string {
+	lines := make([]string, 0, len(e.problems))
+	for _, p := range e.problems {
+		file := e.file
+		if p.file != "" {
+			file = p.file
+		}
+
+		switch {
+		case file != "" && p.line > 0 && p.column > 0:
+			lines = append(lines, fmt.Sprintf("%s:%d:%d: %s", file, p.line, p.column, p.message))
+		case file != "" && p.line > 0:
+			lines = append(lines, fmt.Sprintf("%s:%d: %s", file, p.line, p.message))
+		case file != "":
+			lines = append(lines, fmt.Sprintf("%s: %s", file, p.message))
+		default:
+			lines = append(lines, p.message)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Converts an error into configuration problems at a path, keeping existing problems
+func problemsOf(err error, path string) configProblems {
+	var ce *configError
+	if errors.As(err, &ce) {
+		return ce.problems
+	}
+	return configProblems{{path: path, message: err.Error()}}
+}
+
+// Decodes a configuration document, reporting unknown fields and invalid values
+func decodeConfig(root *yaml.Node, config *Config) configProblems {
+	var problems configProblems
+	findUnknownFields(root, reflect.TypeOf(config).Elem(), "", &problems)
+	if err := root.Decode(config); err != nil {
+		problems = append(problems, yamlProblems(err, root)...)
+	}
+	return problems
+}
+
+// Converts YAML syntax and type errors into problems, locating columns in the document when possible
+func yamlProblems(err error, root *yaml.Node) configProblems {
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	var problems configProblems
+	for _, message := range messages {
+		m := yamlErrorLine.FindStringSubmatch(message)
+		if m == nil {
+			problems = append(problems, configProblem{message: strings.TrimPrefix(message, "yaml: ")})
+			continue
+		}
+
+		line, _ := strconv.Atoi(m[1])
+		problem := configProblem{line: line, message: m[2]}
+		if root != nil {
+			var value string
+			if v := yamlErrorValue.FindStringSubmatch(m[2]); v != nil {
+				value = v[1]
+			}
+			problem.column = columnOf(root, line, value)
+		}
+		problems = append(problems, problem)
+	}
+	return problems
+}
+
+// Returns the column of the node on a line with the given value, or of the last node on the line
+func columnOf(root *yaml.Node, line int, value string) int {
+	column := 0
+	var walk func(n *yaml.Node) bool
+	walk = func(n *yaml.Node) bool {
+		if n.Line == line && n.Kind == yaml.ScalarNode {
+			if value != "" && n.Value == value {
+				column = n.Column
+				return true
+			}
+			column = n.Column
+		}
+		for _, child := range n.Content {
+			if walk(child) {
+				return true
+			}
+		}
+		return false
+	}
+	walk(root)
+	return column
+}
+
+// Reports mapping keys that do not correspond to a field of the target type
+func findUnknownFields(n *yaml.Node, t reflect.Type, path string, problems *configProblems) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, child := range n.Content {
+			findUnknownFields(child, t, path, problems)
+		}
+	case yaml.AliasNode:
+		findUnknownFields(n.Alias, t, path, problems)
+	case yaml.SequenceNode:
+		if t.Kind() != reflect.Slice {
+			return
+		}
+		for i, item := range n.Content {
+			findUnknownFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case yaml.MappingNode:
+		switch t.Kind() {
+		case reflect.Map:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				findUnknownFields(n.Content[i+1], t.Elem(), joinConfigPath(path, n.Content[i].Value), problems)
+			}
+		case reflect.Struct:
+			if t == reflect.TypeOf(yaml.Node{}) {
+				return
+			}
+			fields := yamlFields(t)
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				key := n.Content[i]
+				fieldType, ok := fields[key.Value]
+				if !ok {
+					message := "unknown field " + key.Value
+					if path != "" {
+						message += " in " + path
+					}
+					if suggestion := suggestField(key.Value, fields); suggestion != "" {
+						message += ", did you mean " + suggestion + "?"
+					}
+					*problems = append(*problems, configProblem{
+						path:    joinConfigPath(path, key.Value),
+						line:    key.Line,
+						column:  key.Column,
+						message: message,
+					})
+					continue
+				}
+				findUnknownFields(n.Content[i+1], fieldType, joinConfigPath(path, key.Value), problems)
+			}
+		}
+	}
+}
+
+// Returns the YAML field names of a struct type and their types
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// Returns the known field closest to an unknown one, or an empty string if none is close
+func suggestField(name string, fields map[string]reflect.Type) string {
+	best, bestDistance := "", 3
+	for field := range fields {
+		if strings.EqualFold(field, name) {
+			return field
+		}
+		if d := editDistance(strings.ToLower(field), strings.ToLower(name)); d < bestDistance || (d == bestDistance && field < best) {
+			best, bestDistance = field, d
+		}
+	}
+	if bestDistance > 2 {
+		return ""
+	}
+	return best
+}
+
+// Returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// Appends a key to a configuration path
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Fills in the positions and files of problems from the configuration document
+func locateProblems(root *yaml.Node, files map[*yaml.Node]string, problems configProblems) {
+	for i := range problems {
+		if problems[i].line > 0 || problems[i].path == "" {
+			continue
+		}
+		if n := lookupNode(root, problems[i].path); n != nil {
+			problems[i].line, problems[i].column = n.Line, n.Column
+			problems[i].file = files[n]
+		}
+	}
+}
+
+// Returns the node at a configuration path, or the closest existing ancestor
+func lookupNode(root *yaml.Node, path string) *yaml.Node {
+	n := root
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+
+	found := n
+	for _, m := range configPathSegment.FindAllStringSubmatch(path, -1) {
+		if n.Kind == yaml.AliasNode {
+			n = n.Alias
+		}
+
+		var next *yaml.Node
+		switch {
+		case m[2] != "" && n.Kind == yaml.SequenceNode:
+			if index, _ := strconv.Atoi(m[2]); index < len(n.Content) {
+				next = n.Content[index]
+			}
+		case m[1] != "" && n.Kind == yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == m[1] {
+					next = n.Content[i+1]
+					break
+				}
+			}
+		}
+		if next == nil {
+			return found
+		}
+		n, found = next, next
+	}
+	return found
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigProblems(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{
+			name: "valid configuration",
+			config: `endpoints:
+  - name: "Production"
+    url: "https://api1.example.com/config"
+  - name: "Staging"
+    url: "https://api2.example.com/config"
+settings:
+  jsonPath: "$.features"
+`,
+		},
+		{
+			name: "unknown fields and invalid values",
+			config: `endpoints:
+  - name: "Production"
+    url: "https://api1.example.com/config"
+    auht: "Bearer token123"
+  - name: "Staging"
+    url: "https://"
+settings:
+  timeout: soon
+  ignoreKeys: ["id"]
+  jsonpath: "$.features"
+`,
+			want: []string{
+				"config.yaml:4:5: unknown field auht in endpoints[0], did you mean auth?",
+				"config.yaml:6:10: endpoint Staging: URL has no host: https://",
+				"config.yaml:8:12: cannot unmarshal !!str `soon` into int",
+				"config.yaml:9:3: unknown field ignoreKeys in settings, did you mean ignoredKeys?",
+				"config.yaml:10:3: unknown field jsonpath in settings, did you mean jsonPath?",
+			},
+		},
+		{
+			name: "validation problems",
+			config: `endpoints:
+  - name: "Production"
+    url: "ftp://api1.example.com/config"
+  - name: "Production"
+    url: "https://api2.example.com/config"
+settings:
+  jsonPath: "$[?"
+  mode: "shape"
+`,
+			want: []string{
+				"config.yaml:3:10: endpoint Production: unsupported endpoint source: ftp://api1.example.com/config",
+				"config.yaml:4:11: duplicate endpoint name: Production",
+				"config.yaml:7:13: invalid jsonPath: jsonpath: unexpected eof at position 4",
+				"config.yaml:8:9: unknown comparison mode: shape",
+			},
+		},
+		{
+			name: "endpoint extraction problems",
+			config: `endpoints:
+  - name: "v1"
+    url: "https://api1.example.com/config"
+    jsonPath: "$.config["
+  - name: "v2"
+    url: "https://api2.example.com/config"
+    fieldMap:
+      displayName: "names[0]"
+`,
+			want: []string{
+				"config.yaml:4:15: endpoint v1: invalid jsonPath: jsonpath: unexpected eof at position 10",
+				"config.yaml:8:7: endpoint v2: fieldMap: path \"names[0]\" must end with a key",
+			},
+		},
+		{
+			name:   "missing endpoints",
+			config: "settings:\n  timeout: 10\n",
+			want:   []string{"config.yaml:1:1: at least two endpoints are required for comparison"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.yaml")
+			if err := os.WriteFile(path, []byte(tc.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := LoadConfig(path, "")
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			var ce *configError
+			if !errors.As(err, &ce) {
+				t.Fatalf("Expected configuration error, got %v", err)
+			}
+			var got []string
+			for _, p := range ce.problems {
+				line := (&configError{file: ce.file, problems: configProblems{p}}).Error()
+				got = append(got, strings.TrimPrefix(line, dir+string(filepath.Separator)))
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Unexpected problems:\n got: %q\nwant: %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSuggestField(t *testing.T) {
+	fields := yamlFields(reflect.TypeOf(Settings{}))
+
+	testCases := map[string]string{
+		"jsonpath":   "jsonPath",
+		"ignoreKeys": "ignoredKeys",
+		"timout":     "timeout",
+		"retries":    "",
+	}
+	for name, want := range testCases {
+		if got := suggestField(name, fields); got != want {
+			t.Errorf("suggestField(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
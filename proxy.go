@@ -0,0 +1,481 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Default settings of the proxy mode
+const (
+	proxyReportPath        = "/_rest-compare/report"
+	defaultMaxComparisons  = 64
+	maxProxyBodySize       = 10 << 20
+	statusDifferencePath   = "[status]"
+	unmatchedRoutePattern  = "*"
+	defaultProxySampleRate = 1.0
+)
+
+// Methods mirrored to the candidate by default
+var defaultMirrorMethods = []string{http.MethodGet}
+
+// Headers that apply to a single connection and must not be forwarded
+var hopByHopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// Represents shadow traffic proxy settings
+type ProxySettings struct {
+	SampleRate *float64     `yaml:"sampleRate,omitempty"` // Fraction of requests mirrored to the candidate (default: 1, 0 pauses mirroring)
+	Methods    []string     `yaml:"methods,omitempty"`    // Methods mirrored to the candidate (default: GET)
+	Routes     []ProxyRoute `yaml:"routes,omitempty"`
+}
+
+// Represents per-route comparison rules of the proxy
+type ProxyRoute struct {
+	Path        string   `yaml:"path"`                  // Path prefix of the route
+	IgnoredKeys []string `yaml:"ignoredKeys,omitempty"` // Keys ignored in addition to Settings.IgnoredKeys
+}
+
+// Represents the shadow traffic proxy
+type proxy struct {
+	primary   Endpoint
+	candidate Endpoint
+	config    *Config
+	client    *http.Client
+	stats     *proxyStats
+	slots     chan struct{} // Limits pending comparisons
+}
+
+// Represents aggregated comparison statistics
+type proxyStats struct {
+	mu      sync.Mutex
+	dropped int
+	routes  map[string]*routeStats
+}
+
+// Represents comparison statistics of a single route
+type routeStats struct {
+	compared  int
+	different int
+	errors    int
+	paths     map[string]int
+}
+
+// Represents the aggregated report in JSON form
+type proxyReport struct {
+	Dropped int                `json:"dropped"`
+	Routes  []proxyRouteReport `json:"routes"`
+}
+
+// Represents the report of a single route
+type proxyRouteReport struct {
+	Route          string            `json:"route"`
+	Compared       int               `json:"compared"`
+	Different      int               `json:"different"`
+	Errors         int               `json:"errors"`
+	DifferenceRate float64           `json:"differenceRate"`
+	Paths          []proxyPathReport `json:"paths,omitempty"`
+}
+
+// Represents how often a path differed within a route
+type proxyPathReport struct {
+	Path           string  `json:"path"`
+	Count          int     `json:"count"`
+	DifferenceRate float64 `json:"differenceRate"`
+}
+
+// Represents a buffered HTTP response
+type bufferedResponse struct {
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
+// Runs the proxy subcommand and returns the exit code
+func runProxy(args []string) int {
+	fs := newCommandFlagSet("proxy", "config.yaml [--listen :8080] [--max-pending 64]")
+	profile := profileFlag(fs)
+	listen := fs.String("listen", defaultListenAddr, "address to listen on")
+	maxComparisons := fs.Int("max-pending", defaultMaxComparisons, "maximum number of pending comparisons")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return flagErrorCode(err)
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return 2
+	}
+	if *maxComparisons <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: max-pending must be positive")
+		return 2
+	}
+
+	config, err := LoadConfig(positional[0], *profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config file: %v\n", err)
+		return 2
+	}
+
+	p := newProxy(config, *maxComparisons)
+	for _, endpoint := range []Endpoint{p.primary, p.candidate} {
+		if !isHTTPSource(endpoint.URL) {
+			fmt.Fprintf(os.Stderr, "Error: endpoint %s must be an http or https URL\n", endpoint.Name)
+			return 2
+		}
+	}
+	httpServer := &http.Server{
+		Addr:              *listen,
+		Handler:           p,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("Proxying %s to %s (primary) and %s (candidate)", *listen, p.primary.URL, p.candidate.URL)
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error shutting down: %v\n", err)
+	}
+
+	printProxyReport(p.stats.report())
+	return 0
+}
+
+// Creates a proxy for the first two endpoints of the configuration
+func newProxy(config *Config, maxComparisons int) *proxy {
+	primary, candidate := config.GetDefaultEndpoints()
+	return &proxy{
+		primary:   primary,
+		candidate: candidate,
+		config:    config,
+		client:    &http.Client{Timeout: time.Duration(config.GetTimeout()) * time.Second},
+		stats:     &proxyStats{routes: make(map[string]*routeStats)},
+		slots:     make(chan struct{}, maxComparisons),
+	}
+}
+
+// Forwards the request to the primary and mirrors it to the candidate
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == proxyReportPath {
+		writeJSON(w, http.StatusOK, p.stats.report())
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxProxyBodySize))
+	if err != nil {
+		http.Error(w, "error reading request body", http.StatusBadRequest)
+		return
+	}
+
+	primaryResp, err := p.forward(r.Context(), p.primary, r, body)
+	if err != nil {
+		log.Printf("Error forwarding to primary: %v", err)
+		http.Error(w, "error forwarding request", http.StatusBadGateway)
+		return
+	}
+
+	// Return the primary response to the client
+	copyHeaders(w.Header(), primaryResp.header)
+	w.WriteHeader(primaryResp.statusCode)
+	w.Write(primaryResp.body)
+
+	if !p.shouldMirror(r) {
+		return
+	}
+
+	// Compare asynchronously, dropping the comparison if too many are pending
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		p.stats.recordDropped()
+		return
+	}
+	outgoing := r.Clone(context.Background())
+	go func() {
+		defer func() { <-p.slots }()
+		p.mirror(outgoing, body, primaryResp)
+	}()
+}
+
+// Reports whether a request is sampled for mirroring
+func (p *proxy) shouldMirror(r *http.Request) bool {
+	methods := p.config.Proxy.Methods
+	if len(methods) == 0 {
+		methods = defaultMirrorMethods
+	}
+
+	allowed := false
+	for _, method := range methods {
+		if strings.EqualFold(method, r.Method) {
+			allowed = true
+			break
+		}
+	}
+	return allowed && rand.Float64() < p.config.GetProxySampleRate()
+}
+
+// Sends the request to the candidate and records the comparison with the primary response
+func (p *proxy) mirror(r *http.Request, body []byte, primaryResp *bufferedResponse) {
+	route := p.matchRoute(r.URL.Path)
+	routeName := r.Method + " " + unmatchedRoutePattern
+	ignoredKeys := p.config.GetIgnoredKeys()
+	if route != nil {
+		routeName = r.Method + " " + route.Path
+		ignoredKeys = append(append([]string{}, ignoredKeys...), route.IgnoredKeys...)
+	}
+
+	candidateResp, err := p.forward(context.Background(), p.candidate, r, body)
+	if err != nil {
+		log.Printf("Error forwarding to candidate: %v", err)
+		p.stats.recordError(routeName)
+		return
+	}
+
+	opts := newCompareOptions(p.config)
+	opts.ignoreKeys = ignoredKeys
+	diffs, err := p.compareResponses(primaryResp, candidateResp, opts)
+	if err != nil {
+		log.Printf("Error comparing %s %s: %v", r.Method, r.URL.RequestURI(), err)
+		p.stats.recordError(routeName)
+		return
+	}
+
+	p.stats.recordComparison(routeName, diffs)
+}
+
+// Returns the route with the longest matching path prefix
+func (p *proxy) matchRoute(path string) *ProxyRoute {
+	var best *ProxyRoute
+	for i := range p.config.Proxy.Routes {
+		route := &p.config.Proxy.Routes[i]
+		if strings.HasPrefix(path, route.Path) && (best == nil || len(route.Path) > len(best.Path)) {
+			best = route
+		}
+	}
+	return best
+}
+
+// Forwards a request to the endpoint and buffers the response
+func (p *proxy) forward(ctx context.Context, endpoint Endpoint, r *http.Request, body []byte) (*bufferedResponse, error) {
+	target := strings.TrimSuffix(endpoint.URL, "/") + r.URL.RequestURI()
+	req, err := http.NewRequestWithContext(ctx, r.Method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	copyHeaders(req.Header, r.Header)
+	// Let the transport handle compression so bodies can be compared
+	req.Header.Del("Accept-Encoding")
+	for name, value := range endpoint.Headers {
+		req.Header.Set(name, value)
+	}
+	auth, err := authorization(endpoint, p.client.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bufferedResponse{statusCode: resp.StatusCode, header: resp.Header, body: respBody}, nil
+}
+
+// Compares the status codes and JSON bodies of the primary and candidate responses like replayed requests,
+// with the JSON paths, field maps and transforms of the configuration
+func (p *proxy) compareResponses(a, b *bufferedResponse, opts *compareOptions) ([]diffInfo, error) {
+	return compareResponseBodies(p.primary, p.candidate, a.statusCode, b.statusCode, a.body, b.body,
+		p.config.GetJSONPathSelectors(), p.config.GetJSONPathResults(), p.config.GetTransforms(), opts)
+}
+
+// Compares status codes and the JSON bodies extracted with the endpoints' JSON paths (or an optional default) and field maps,
+// then reshaped by the transforms
+func compareResponseBodies(endpointA, endpointB Endpoint, statusA, statusB int, bodyA, bodyB []byte, selectors jsonPathSelectors, results string, transforms []Transform, opts *compareOptions) ([]diffInfo, error) {
+	var diffs []diffInfo
+	if statusA != statusB {
+		diffs = append(diffs, diffInfo{statusDifferencePath, statusA, statusB})
+	}
+
+	// Responses without a body (e.g. 204 or HEAD) only differ by their status
+	if len(bytes.TrimSpace(bodyA)) == 0 && len(bytes.TrimSpace(bodyB)) == 0 {
+		return diffs, nil
+	}
+
+	dataA, err := parseJSON(bodyA)
+	if err != nil {
+		return nil, fmt.Errorf("response A is not JSON: %w", err)
+	}
+	dataB, err := parseJSON(bodyB)
+	if err != nil {
+		return nil, fmt.Errorf("response B is not JSON: %w", err)
+	}
+
+	if dataA, err = extractEndpointData(endpointA, dataA, selectors, results); err != nil {
+		return nil, fmt.Errorf("Error processing response A: %v", err)
+	}
+	if dataB, err = extractEndpointData(endpointB, dataB, selectors, results); err != nil {
+		return nil, fmt.Errorf("Error processing response B: %v", err)
+	}
+	if dataA, dataB, err = transformDocuments(dataA, dataB, transforms); err != nil {
+		return nil, err
+	}
+
+	return append(diffs, diffJSON(dataA, dataB, opts)...), nil
+}
+
+// Copies headers except hop-by-hop headers
+func copyHeaders(dst, src http.Header) {
+	for name, values := range src {
+		dst[name] = append([]string(nil), values...)
+	}
+	for _, name := range hopByHopHeaders {
+		dst.Del(name)
+	}
+}
+
+// Records a comparison result
+func (s *proxyStats) recordComparison(route string, diffs []diffInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.route(route)
+	stats.compared++
+	if len(diffs) > 0 {
+		stats.different++
+	}
+	for _, diff := range diffs {
+		stats.paths[diff.path]++
+	}
+}
+
+// Records a failed comparison
+func (s *proxyStats) recordError(route string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.route(route).errors++
+}
+
+// Records a comparison dropped because too many were pending
+func (s *proxyStats) recordDropped() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropped++
+}
+
+// Returns the statistics of a route, creating them if needed (caller must hold the lock)
+func (s *proxyStats) route(route string) *routeStats {
+	stats, ok := s.routes[route]
+	if !ok {
+		stats = &routeStats{paths: make(map[string]int)}
+		s.routes[route] = stats
+	}
+	return stats
+}
+
+// Builds the aggregated report
+func (s *proxyStats) report() proxyReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := proxyReport{Dropped: s.dropped, Routes: []proxyRouteReport{}}
+	for name, stats := range s.routes {
+		routeReport := proxyRouteReport{
+			Route:          name,
+			Compared:       stats.compared,
+			Different:      stats.different,
+			Errors:         stats.errors,
+			DifferenceRate: rate(stats.different, stats.compared),
+		}
+		for path, count := range stats.paths {
+			routeReport.Paths = append(routeReport.Paths, proxyPathReport{path, count, rate(count, stats.compared)})
+		}
+
+		// Most frequent differences first
+		sort.Slice(routeReport.Paths, func(i, j int) bool {
+			if routeReport.Paths[i].Count != routeReport.Paths[j].Count {
+				return routeReport.Paths[i].Count > routeReport.Paths[j].Count
+			}
+			return routeReport.Paths[i].Path < routeReport.Paths[j].Path
+		})
+		report.Routes = append(report.Routes, routeReport)
+	}
+
+	sort.Slice(report.Routes, func(i, j int) bool {
+		return report.Routes[i].Route < report.Routes[j].Route
+	})
+	return report
+}
+
+// Returns the fraction of count in total
+func rate(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) / float64(total)
+}
+
+// Prints the aggregated report
+func printProxyReport(report proxyReport) {
+	fmt.Println("Shadow traffic report:")
+	for _, route := range report.Routes {
+		fmt.Printf("- Route: %s\n  Compared: %d, Different: %d (%.1f%%), Errors: %d\n",
+			route.Route, route.Compared, route.Different, route.DifferenceRate*100, route.Errors)
+		for _, path := range route.Paths {
+			fmt.Printf("  %s: %d (%.1f%%)\n", path.Path, path.Count, path.DifferenceRate*100)
+		}
+	}
+	if report.Dropped > 0 {
+		fmt.Printf("Dropped comparisons: %d\n", report.Dropped)
+	}
+}
+
+// Validates the proxy settings
+func validateProxySettings(settings *ProxySettings) error {
+	if settings.SampleRate != nil && (*settings.SampleRate < 0 || *settings.SampleRate > 1) {
+		return errors.New("proxy sample rate must be between 0 and 1")
+	}
+	for _, route := range settings.Routes {
+		if !strings.HasPrefix(route.Path, "/") {
+			return errors.New("proxy route path must start with /: " + route.Path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestProxy(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"api","requestId":"p1","timeout":30}`))
+	}))
+	defer primary.Close()
+
+	candidate := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/users/1" {
+			w.Write([]byte(`{"name":"api","requestId":"c1","timeout":60}`))
+			return
+		}
+		w.Write([]byte(`{"name":"api","requestId":"c1","timeout":30}`))
+	}))
+	defer candidate.Close()
+
+	config := &Config{
+		Endpoints: []Endpoint{
+			{Name: "Primary", URL: primary.URL},
+			{Name: "Candidate", URL: candidate.URL},
+		},
+		Settings: Settings{IgnoredKeys: []string{"requestId"}},
+		Proxy: ProxySettings{
+			Routes: []ProxyRoute{
+				{Path: "/users/"},
+				{Path: "/status", IgnoredKeys: []string{"timeout"}},
+			},
+		},
+	}
+	setDefaults(config)
+	p := newProxy(config, 8)
+
+	for _, path := range []string{"/users/1", "/users/2", "/status", "/other"} {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		body, _ := io.ReadAll(rec.Body)
+		if rec.Code != http.StatusOK || string(body) != `{"name":"api","requestId":"p1","timeout":30}` {
+			t.Errorf("Expected primary response for %s, got %d: %s", path, rec.Code, body)
+		}
+	}
+
+	// POST requests are not mirrored by default
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("POST", "/users/1", nil))
+
+	// Wait for the asynchronous comparisons
+	deadline := time.Now().Add(5 * time.Second)
+	for len(p.slots) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	report := p.stats.report()
+	routes := make(map[string]proxyRouteReport)
+	for _, route := range report.Routes {
+		routes[route.Route] = route
+	}
+
+	users := routes["GET /users/"]
+	if users.Compared != 2 || users.Different != 1 || users.DifferenceRate != 0.5 {
+		t.Errorf("Unexpected users route report: %+v", users)
+	}
+	if len(users.Paths) != 1 || users.Paths[0].Path != "timeout" || users.Paths[0].Count != 1 {
+		t.Errorf("Unexpected users path report: %+v", users.Paths)
+	}
+	if status := routes["GET /status"]; status.Compared != 1 || status.Different != 0 {
+		t.Errorf("Unexpected status route report: %+v", status)
+	}
+	if other := routes["GET *"]; other.Compared != 1 {
+		t.Errorf("Unexpected unmatched route report: %+v", other)
+	}
+	if _, ok := routes["POST /users/"]; ok {
+		t.Error("POST requests should not be mirrored")
+	}
+}
+
+func TestProxySampleRate(t *testing.T) {
+	var config Config
+	if err := yaml.Unmarshal([]byte(`endpoints:
+  - {name: primary, url: "http://primary"}
+  - {name: candidate, url: "http://candidate"}
+proxy:
+  sampleRate: 0
+`), &config); err != nil {
+		t.Fatal(err)
+	}
+	setDefaults(&config)
+
+	// An explicit 0 pauses mirroring instead of falling back to the default
+	if rate := config.GetProxySampleRate(); rate != 0 {
+		t.Errorf("Expected sample rate 0, got %v", rate)
+	}
+	p := newProxy(&config, 8)
+	for i := 0; i < 100; i++ {
+		if p.shouldMirror(httptest.NewRequest("GET", "/users/1", nil)) {
+			t.Fatal("Expected no request to be mirrored")
+		}
+	}
+
+	// Without a sample rate, every request is mirrored
+	if rate := (&Config{}).GetProxySampleRate(); rate != defaultProxySampleRate {
+		t.Errorf("Expected default sample rate, got %v", rate)
+	}
+}
+
+func TestProxyComparisonPipeline(t *testing.T) {
+	handler := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/empty" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(body))
+		}
+	}
+	primary := httptest.NewServer(handler(`{"data":{"name":"api","deleted":null},"meta":1}`))
+	defer primary.Close()
+	candidate := httptest.NewServer(handler(`{"data":{"title":"api"},"meta":2}`))
+	defer candidate.Close()
+
+	config := &Config{
+		Endpoints: []Endpoint{
+			{Name: "Primary", URL: primary.URL},
+			{Name: "Candidate", URL: candidate.URL, FieldMap: map[string]string{"title": "name"}},
+		},
+		Settings: Settings{
+			JSONPath:   jsonPathSelectors{{Path: "$.data"}},
+			Transforms: []Transform{{Op: transformDropNulls}},
+		},
+		Proxy: ProxySettings{Methods: []string{http.MethodGet, http.MethodHead}},
+	}
+	setDefaults(config)
+	p := newProxy(config, 8)
+
+	for _, path := range []string{"/users/1", "/empty"} {
+		p.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	p.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("HEAD", "/users/1", nil))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(p.slots) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The JSON path, field map and transforms apply, and bodyless responses are equal
+	report := p.stats.report()
+	if len(report.Routes) != 2 {
+		t.Fatalf("Expected GET and HEAD routes, got %+v", report.Routes)
+	}
+	for _, route := range report.Routes {
+		if route.Compared == 0 || route.Different != 0 || route.Errors != 0 {
+			t.Errorf("Unexpected route report: %+v", route)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request corpus formats
+const (
+	requestsFormatHAR   = "har"
+	requestsFormatLines = "lines"
+)
+
+// Default number of requests replayed concurrently
+const defaultReplayConcurrency = 4
+
+// Represents the settings of a replayed request corpus
+type RequestsSettings struct {
+	File        string `yaml:"file"`                  // HAR file or newline-delimited request list
+	Format      string `yaml:"format,omitempty"`      // har or lines (default: by file extension)
+	Concurrency int    `yaml:"concurrency,omitempty"` // Number of requests replayed concurrently
+}
+
+// Represents a request replayed against both endpoints
+type replayRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Query   string            `json:"query,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Represents the result of a single replayed request
+type replayResult struct {
+	request replayRequest
+	diffs   []diffInfo
+	err     error
+}
+
+// Represents the subset of the HAR format used to read requests
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method   string `json:"method"`
+				URL      string `json:"url"`
+				PostData *struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// Loads a request corpus in the given format
+func loadReplayRequests(path, format string) ([]replayRequest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "" {
+		format = requestsFormatLines
+		if strings.EqualFold(filepath.Ext(path), ".har") {
+			format = requestsFormatHAR
+		}
+	}
+
+	switch format {
+	case requestsFormatHAR:
+		return parseHAR(data)
+	case requestsFormatLines:
+		return parseRequestLines(data)
+	default:
+		return nil, errors.New("unknown requests format: " + format)
+	}
+}
+
+// Parses the requests of a HAR file
+func parseHAR(data []byte) ([]replayRequest, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("invalid HAR file: %w", err)
+	}
+
+	var requests []replayRequest
+	for i, entry := range har.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: invalid URL: %w", i+1, err)
+		}
+
+		request := replayRequest{
+			Method: strings.ToUpper(entry.Request.Method),
+			Path:   u.EscapedPath(),
+			Query:  u.RawQuery,
+		}
+		if postData := entry.Request.PostData; postData != nil && postData.Text != "" {
+			request.Body = json.RawMessage(postData.Text)
+			if postData.MimeType != "" {
+				request.Headers = map[string]string{"Content-Type": postData.MimeType}
+			}
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, nil
+}
+
+// Parses newline-delimited requests, either JSON records or "METHOD /path?query" lines
+func parseRequestLines(data []byte) ([]replayRequest, error) {
+	var requests []replayRequest
+
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxProxyBodySize)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip blank lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		request, err := parseRequestLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		requests = append(requests, request)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// Parses a single request line
+func parseRequestLine(line string) (replayRequest, error) {
+	var request replayRequest
+
+	if strings.HasPrefix(line, "{") {
+		if err := json.Unmarshal([]byte(line), &request); err != nil {
+			return request, err
+		}
+		request.Method = strings.ToUpper(request.Method)
+	} else {
+		fields := strings.Fields(line)
+		target := fields[0]
+		request.Method = http.MethodGet
+		if len(fields) > 1 {
+			request.Method = strings.ToUpper(fields[0])
+			target = fields[1]
+		}
+		request.Path, request.Query, _ = strings.Cut(target, "?")
+	}
+
+	if request.Method == "" {
+		request.Method = http.MethodGet
+	}
+	if len(request.Body) > 0 && request.Headers["Content-Type"] == "" {
+		if request.Headers == nil {
+			request.Headers = make(map[string]string)
+		}
+		request.Headers["Content-Type"] = "application/json"
+	}
+	if !strings.HasPrefix(request.Path, "/") {
+		return request, errors.New("request path must start with /: " + request.Path)
+	}
+	return request, nil
+}
+
+// Validates the request corpus settings
+func validateRequestsSettings(settings *RequestsSettings, endpoints []Endpoint) error {
+	if settings.File == "" {
+		return nil
+	}
+
+	switch settings.Format {
+	case "", requestsFormatHAR, requestsFormatLines:
+	default:
+		return errors.New("unknown requests format: " + settings.Format)
+	}
+	if settings.Concurrency < 0 {
+		return errors.New("requests concurrency must not be negative")
+	}
+
+	// Requests are replayed against the base URLs of the endpoints
+	for _, endpoint := range endpoints {
+		if !isHTTPSource(endpoint.URL) {
+			return fmt.Errorf("endpoint %s must be an http or https URL to replay requests", endpoint.Name)
+		}
+	}
+	return nil
+}
+
+// Returns the request target relative to a base URL
+func (r replayRequest) target() string {
+	if r.Query == "" {
+		return r.Path
+	}
+	return r.Path + "?" + r.Query
+}
+
+// Returns a short description of the request
+func (r replayRequest) String() string {
+	return r.Method + " " + r.target()
+}
+
+// Replays every request against both endpoints and compares the responses
+func runReplay(config *Config, requests []replayRequest) []replayResult {
+	results := make([]replayResult, len(requests))
+
+	concurrency := config.Requests.Concurrency
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = replayOne(config, requests[i])
+			}
+		}()
+	}
+
+	for i := range requests {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// Replays a single request against both endpoints
+func replayOne(config *Config, request replayRequest) replayResult {
+	endpointA, endpointB := config.GetDefaultEndpoints()
+	timeout := time.Duration(config.GetTimeout()) * time.Second
+
+	respA, err := sendWithAuth(endpointA, request.toHTTP(endpointA), timeout)
+	if err != nil {
+		return replayResult{request: request, err: fmt.Errorf("Error fetching from endpoint A: %v", err)}
+	}
+	respB, err := sendWithAuth(endpointB, request.toHTTP(endpointB), timeout)
+	if err != nil {
+		return replayResult{request: request, err: fmt.Errorf("Error fetching from endpoint B: %v", err)}
+	}
+
+	diffs, err := compareResponseBodies(endpointA, endpointB, respA.statusCode, respB.statusCode, respA.body, respB.body,
+		config.GetJSONPathSelectors(), config.GetJSONPathResults(), config.GetTransforms(), newCompareOptions(config))
+	return replayResult{request: request, diffs: diffs, err: err}
+}
+
+// Converts the request into an HTTP request against the endpoint's base URL
+func (r replayRequest) toHTTP(endpoint Endpoint) httpRequest {
+	return httpRequest{
+		method:  r.Method,
+		url:     strings.TrimSuffix(endpoint.URL, "/") + r.target(),
+		body:    r.Body,
+		headers: r.Headers,
+	}
+}
+
+// Prints per-request results and a summary, returning the exit code
+func reportReplay(results []replayResult) int {
+	identical, different, failed := 0, 0, 0
+
+	for _, result := range results {
+		switch {
+		case result.err != nil:
+			failed++
+			fmt.Printf("- %s: error: %s\n", result.request, secrets.redact(result.err.Error()))
+		case len(result.diffs) > 0:
+			different++
+			fmt.Printf("- %s: different\n", result.request)
+			for _, diff := range result.diffs {
+				fmt.Println(indent(formatDiff(diff), "  "))
+			}
+		default:
+			identical++
+			fmt.Printf("- %s: identical\n", result.request)
+		}
+	}
+
+	fmt.Printf("\nSummary: %d requests, %d identical, %d different, %d errors\n",
+		len(results), identical, different, failed)
+
+	switch {
+	case failed > 0:
+		return 2
+	case different > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Indents every line of a text
+func indent(text, prefix string) string {
+	return prefix + strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
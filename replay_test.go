@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadReplayRequests(t *testing.T) {
+	dir := t.TempDir()
+
+	lines := `# health checks
+/health
+GET /users?page=2
+{"method":"post","path":"/search","body":{"q":"a"}}
+`
+	linesPath := filepath.Join(dir, "requests.txt")
+	if err := os.WriteFile(linesPath, []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	requests, err := loadReplayRequests(linesPath, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []string{"GET /health", "GET /users?page=2", "POST /search"}
+	if len(requests) != len(want) {
+		t.Fatalf("Expected %d requests, got %d", len(want), len(requests))
+	}
+	for i, request := range requests {
+		if request.String() != want[i] {
+			t.Errorf("Request %d = %s, want %s", i, request, want[i])
+		}
+	}
+	if requests[2].Headers["Content-Type"] != "application/json" || string(requests[2].Body) != `{"q":"a"}` {
+		t.Errorf("Unexpected body request: %+v", requests[2])
+	}
+
+	har := `{"log":{"entries":[
+		{"request":{"method":"GET","url":"https://prod.example.com/users/1?expand=true"}},
+		{"request":{"method":"POST","url":"https://prod.example.com/search","postData":{"mimeType":"application/json","text":"{\"q\":\"b\"}"}}}
+	]}}`
+	harPath := filepath.Join(dir, "session.har")
+	if err := os.WriteFile(harPath, []byte(har), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	requests, err = loadReplayRequests(harPath, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(requests) != 2 || requests[0].String() != "GET /users/1?expand=true" || string(requests[1].Body) != `{"q":"b"}` {
+		t.Errorf("Unexpected HAR requests: %+v", requests)
+	}
+
+	badPath := filepath.Join(dir, "bad.txt")
+	if err := os.WriteFile(badPath, []byte("GET users\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadReplayRequests(badPath, ""); err == nil {
+		t.Error("Expected error for relative path")
+	}
+}
+
+func TestRunReplay(t *testing.T) {
+	newBackend := func(version int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/config":
+				fmt.Fprintf(w, `{"version":%d,"name":"api"}`, version)
+			case "/echo":
+				body, _ := io.ReadAll(r.Body)
+				w.Write(body)
+			case "/text":
+				w.Write([]byte("not json"))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{}`))
+			}
+		}))
+	}
+	backendA, backendB := newBackend(1), newBackend(2)
+	defer backendA.Close()
+	defer backendB.Close()
+
+	config := &Config{
+		Endpoints: []Endpoint{
+			{Name: "A", URL: backendA.URL + "/"},
+			{Name: "B", URL: backendB.URL},
+		},
+	}
+	setDefaults(config)
+
+	requests := []replayRequest{
+		{Method: "GET", Path: "/config"},
+		{Method: "POST", Path: "/echo", Body: []byte(`{"a":1}`)},
+		{Method: "GET", Path: "/text"},
+	}
+	results := runReplay(config, requests)
+
+	if len(results[0].diffs) != 1 || results[0].diffs[0].path != "version" {
+		t.Errorf("Expected version difference, got %v", results[0].diffs)
+	}
+	if results[1].err != nil || len(results[1].diffs) != 0 {
+		t.Errorf("Expected identical echo responses, got %v (err: %v)", results[1].diffs, results[1].err)
+	}
+	if results[2].err == nil {
+		t.Error("Expected error for non-JSON response")
+	}
+	if code := reportReplay(results); code != 2 {
+		t.Errorf("Expected exit code 2, got %d", code)
+	}
+}
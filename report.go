@@ -0,0 +1,139 @@
+package main
+
+import (
+	"time"
+)
+
+// Comparison result states
+const (
+	statusIdentical = "identical"
+	statusDifferent = "different"
+	statusError     = "error"
+	statusInvalid   = "invalid"
+)
+
+// Represents a comparison result in JSON form
+type jsonReport struct {
+	Status      string                   `json:"status"` // identical, different, invalid or error
+	ExitCode    int                      `json:"exitCode"`
+	CheckedAt   time.Time                `json:"checkedAt"`
+	EndpointA   jsonEndpoint             `json:"endpointA"`
+	EndpointB   jsonEndpoint             `json:"endpointB"`
+	Error       string                   `json:"error,omitempty"`
+	Differences []jsonDifference         `json:"differences,omitempty"`
+	Expired     []jsonAcceptedDifference `json:"expired,omitempty"`
+	Accepted    []jsonAcceptedDifference `json:"accepted,omitempty"`
+	Stale       []jsonAcceptedEntry      `json:"stale,omitempty"`
+	Noisy       []jsonNoisyPath          `json:"noisy,omitempty"`
+	Violations  []jsonSchemaViolation    `json:"schemaViolations,omitempty"`
+}
+
+// Represents an endpoint in a JSON report
+type jsonEndpoint struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// Represents a difference in a JSON report
+type jsonDifference struct {
+	Selector string      `json:"selector,omitempty"` // Name of the JSON path selector the difference was found in
+	Path     string      `json:"path"`
+	A        interface{} `json:"a"`
+	B        interface{} `json:"b"`
+}
+
+// Represents an accepted difference in a JSON report
+type jsonAcceptedDifference struct {
+	jsonDifference
+	Owner   string `json:"owner"`
+	Expires string `json:"expires"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Represents an accepted difference entry that no longer matches in a JSON report
+type jsonAcceptedEntry struct {
+	Path    string `json:"path"`
+	Owner   string `json:"owner"`
+	Expires string `json:"expires"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Represents a non-deterministic path in a JSON report
+type jsonNoisyPath struct {
+	Path      string   `json:"path"`
+	Endpoints []string `json:"endpoints"`
+}
+
+// Represents a JSON Schema violation in a JSON report
+type jsonSchemaViolation struct {
+	Endpoint string `json:"endpoint"`
+	Location string `json:"location"`
+	Message  string `json:"message"`
+}
+
+// Creates a JSON report from a comparison result or error
+func newJSONReport(endpointA, endpointB Endpoint, result comparisonResult, err error) *jsonReport {
+	report := &jsonReport{
+		CheckedAt: time.Now().UTC(),
+		EndpointA: jsonEndpoint{endpointA.Name, endpointA.URL},
+		EndpointB: jsonEndpoint{endpointB.Name, endpointB.URL},
+	}
+
+	if err != nil {
+		report.Status = statusError
+		report.ExitCode = 2
+		report.Error = secrets.redact(err.Error())
+		return report
+	}
+
+	for _, diff := range result.unaccepted {
+		difference := newJSONDifference(diff)
+		difference.Selector = selectorOf(diff.path, result.selectors)
+		report.Differences = append(report.Differences, difference)
+	}
+	for _, m := range result.expired {
+		difference := newJSONAcceptedDifference(m.diff, m.entry)
+		difference.Selector = selectorOf(m.diff.path, result.selectors)
+		report.Expired = append(report.Expired, difference)
+	}
+	for _, m := range result.accepted {
+		difference := newJSONAcceptedDifference(m.diff, m.entry)
+		difference.Selector = selectorOf(m.diff.path, result.selectors)
+		report.Accepted = append(report.Accepted, difference)
+	}
+	for _, entry := range result.stale {
+		report.Stale = append(report.Stale, jsonAcceptedEntry{entry.Path, entry.Owner, entry.Expires, entry.Reason})
+	}
+	for _, n := range result.noisy {
+		report.Noisy = append(report.Noisy, jsonNoisyPath{n.path, n.endpoints})
+	}
+	for _, v := range result.violations {
+		report.Violations = append(report.Violations, jsonSchemaViolation{v.endpoint, v.location, secrets.redact(v.message)})
+	}
+
+	report.ExitCode = result.exitCode()
+	switch report.ExitCode {
+	case exitSchemaViolation:
+		report.Status = statusInvalid
+	case 1:
+		report.Status = statusDifferent
+	default:
+		report.Status = statusIdentical
+	}
+	return report
+}
+
+// Converts a difference for a JSON report, redacting secret values
+func newJSONDifference(d diffInfo) jsonDifference {
+	return jsonDifference{Path: d.path, A: secrets.redactValue(d.valueA), B: secrets.redactValue(d.valueB)}
+}
+
+// Converts an accepted difference for a JSON report
+func newJSONAcceptedDifference(d diffInfo, entry *AcceptedDifference) jsonAcceptedDifference {
+	return jsonAcceptedDifference{
+		jsonDifference: newJSONDifference(d),
+		Owner:          entry.Owner,
+		Expires:        entry.Expires,
+		Reason:         entry.Reason,
+	}
+}
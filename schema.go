@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/theory/jsonpath"
+	"github.com/theory/jsonpath/spec"
+)
+
+// Exit code for responses violating the JSON Schema
+const exitSchemaViolation = 3
+
+// Represents a JSON Schema validation error of an endpoint
+type schemaViolation struct {
+	endpoint string
+	location string // JSON Pointer to the invalid value
+	message  string
+}
+
+// Compiles a local JSON Schema file, defaulting to draft 2020-12
+func loadSchema(path string) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	return compiler.Compile(path)
+}
+
+// Validates a document against the schema and returns the violations found
+func validateSchema(schema *jsonschema.Schema, endpoint string, data interface{}) ([]schemaViolation, error) {
+	err := schema.Validate(data)
+	if err == nil {
+		return nil, nil
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil, fmt.Errorf("Error validating endpoint %s: %v", endpoint, err)
+	}
+
+	var violations []schemaViolation
+	collectViolations(validationErr, endpoint, &violations)
+	return violations, nil
+}
+
+// Validates each node selected by a JSON path separately, locating violations with JSON Pointers below the node's path
+func validateNodes(schema *jsonschema.Schema, endpoint string, data interface{}) ([]schemaViolation, error) {
+	nodes, _ := data.(map[string]interface{})
+	locations := make([]string, 0, len(nodes))
+	for location := range nodes {
+		locations = append(locations, location)
+	}
+	sort.Strings(locations)
+
+	var violations []schemaViolation
+	for _, location := range locations {
+		found, err := validateSchema(schema, endpoint, nodes[location])
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range found {
+			v.location = formatPointer(normalizedPointer(location) + strings.TrimSuffix(v.location, "/"))
+			violations = append(violations, v)
+		}
+	}
+	return violations, nil
+}
+
+// Collects the innermost validation errors, which describe the actual violations
+func collectViolations(err *jsonschema.ValidationError, endpoint string, violations *[]schemaViolation) {
+	if len(err.Causes) == 0 {
+		*violations = append(*violations, schemaViolation{endpoint, formatPointer(err.InstanceLocation), err.Message})
+		return
+	}
+	for _, cause := range err.Causes {
+		collectViolations(cause, endpoint, violations)
+	}
+}
+
+// Returns a JSON Pointer, using "/" for the document root
+func formatPointer(pointer string) string {
+	if pointer == "" {
+		return "/"
+	}
+	return pointer
+}
+
+// Converts a normalized path, as returned by the JSONPath library, into a JSON Pointer
+func normalizedPointer(location string) string {
+	p, err := jsonpath.Parse(location)
+	if err != nil {
+		return location
+	}
+	var selectors []spec.NormalSelector
+	for _, segment := range p.Query().Segments() {
+		for _, selector := range segment.Selectors() {
+			if normal, ok := selector.(spec.NormalSelector); ok {
+				selectors = append(selectors, normal)
+			}
+		}
+	}
+	return spec.Normalized(selectors...).Pointer()
+}
+
+// Formats a schema violation, redacting secret values
+func formatViolation(v schemaViolation) string {
+	return secrets.redact(fmt.Sprintf("- Endpoint: %s\n  Location: %s\n  Error: %s", v.endpoint, v.location, v.message))
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateSchema(t *testing.T) {
+	schemaJSON := `{
+		"type": "object",
+		"required": ["name", "timeout"],
+		"properties": {
+			"name": {"type": "string"},
+			"timeout": {"type": "number", "minimum": 1},
+			"items": {"type": "array", "items": {"type": "string"}}
+		}
+	}`
+	path := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(path, []byte(schemaJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := loadSchema(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	valid := map[string]interface{}{"name": "api", "timeout": float64(30)}
+	violations, err := validateSchema(schema, "Production", valid)
+	if err != nil || len(violations) != 0 {
+		t.Errorf("Expected no violations, got %v (err: %v)", violations, err)
+	}
+
+	invalid := map[string]interface{}{
+		"timeout": float64(0),
+		"items":   []interface{}{"a", float64(1)},
+	}
+	violations, err = validateSchema(schema, "Staging", invalid)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	locations := make(map[string]bool)
+	for _, v := range violations {
+		if v.endpoint != "Staging" {
+			t.Errorf("Unexpected endpoint: %s", v.endpoint)
+		}
+		locations[v.location] = true
+	}
+	for _, want := range []string{"/", "/timeout", "/items/1"} {
+		if !locations[want] {
+			t.Errorf("Expected violation at %s, got %v", want, violations)
+		}
+	}
+
+	nodes := map[string]interface{}{
+		"$['services'][0]": valid,
+		"$['services'][1]": map[string]interface{}{"name": "db", "timeout": float64(0)},
+	}
+	violations, err = validateNodes(schema, "Staging", nodes)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(violations) != 1 || violations[0].location != "/services/1/timeout" {
+		t.Errorf("Expected one violation at /services/1/timeout, got %v", violations)
+	}
+
+	// Keys are escaped, and a violating node is located at its own path
+	nodes = map[string]interface{}{"$['a/b']['c~d']": "not an object"}
+	violations, err = validateNodes(schema, "Staging", nodes)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(violations) == 0 || violations[0].location != "/a~1b/c~0d" {
+		t.Errorf("Expected a violation at /a~1b/c~0d, got %v", violations)
+	}
+}
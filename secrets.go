@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Replacement of secret values in reports and logs
+const redactedSecret = "[REDACTED]"
+
+// Matches ${env:NAME}, ${file:/path} and ${exec:command} secret references
+var secretRef = regexp.MustCompile(`\$\{([a-z]+):([^}]*)\}`)
+
+// Resolved secret values, redacted from all reports and logs
+var secrets secretRegistry
+
+// Represents the set of resolved secret values
+type secretRegistry struct {
+	mu     sync.RWMutex
+	values []string // Sorted longest first so that overlapping secrets are fully redacted
+}
+
+// Registers a secret value to be redacted
+func (r *secretRegistry) add(value string) {
+	if value == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, v := range r.values {
+		if v == value {
+			return
+		}
+	}
+	r.values = append(r.values, value)
+	sort.Slice(r.values, func(i, j int) bool {
+		return len(r.values[i]) > len(r.values[j])
+	})
+}
+
+// Replaces all registered secret values in the text
+func (r *secretRegistry) redact(text string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, v := range r.values {
+		text = strings.ReplaceAll(text, v, redactedSecret)
+	}
+	return text
+}
+
+// Replaces registered secret values in the strings of a JSON value
+func (r *secretRegistry) redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		return r.redact(value)
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			result[r.redact(key)] = r.redactValue(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = r.redactValue(item)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// Writer that redacts secret values, used for log output
+type redactingWriter struct {
+	w io.Writer
+}
+
+// Writes the redacted data, reporting the length of the original data
+func (rw redactingWriter) Write(p []byte) (int, error) {
+	if _, err := rw.w.Write([]byte(secrets.redact(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Resolves the secret references in a value and registers the resolved secrets
+func resolveSecretRefs(value, baseDir string, timeout time.Duration) (string, error) {
+	var resolveErr error
+
+	resolved := secretRef.ReplaceAllStringFunc(value, func(ref string) string {
+		if resolveErr != nil {
+			return ref
+		}
+		match := secretRef.FindStringSubmatch(ref)
+		secret, err := resolveSecret(match[1], match[2], baseDir, timeout)
+		if err != nil {
+			resolveErr = fmt.Errorf("resolving %s: %w", ref, err)
+			return ref
+		}
+		secrets.add(secret)
+		return secret
+	})
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+
+	return resolved, nil
+}
+
+// Resolves a single secret reference
+func resolveSecret(kind, name, baseDir string, timeout time.Duration) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("%s secret reference is empty", kind)
+	}
+
+	switch kind {
+	case "env":
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	case "file":
+		data, err := os.ReadFile(resolvePath(baseDir, name))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case "exec":
+		resp, err := runCommand(name, timeout)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(resp.body), "\r\n"), nil
+	default:
+		return "", fmt.Errorf("unknown secret reference type: %s", kind)
+	}
+}
+
+// Resolves the secret references in the authentication, headers and step bodies of the endpoints,
+// reporting every reference that cannot be resolved
+func resolveEndpointSecrets(endpoints []Endpoint, baseDir string, timeout time.Duration) error {
+	var problems configProblems
+
+	// Resolves a value in place
+	resolve := func(value *string, path, name string) {
+		resolved, err := resolveSecretRefs(*value, baseDir, timeout)
+		if err != nil {
+			problems.add(path, "endpoint %s: %v", name, err)
+			return
+		}
+		*value = resolved
+	}
+
+	for i := range endpoints {
+		endpoint := &endpoints[i]
+		path := fmt.Sprintf("endpoints[%d]", i)
+
+		resolve(&endpoint.Auth, path+".auth", endpoint.Name)
+		for header, value := range endpoint.Headers {
+			resolve(&value, path+".headers."+header, endpoint.Name)
+			endpoint.Headers[header] = value
+		}
+		if endpoint.OAuth2 != nil {
+			resolve(&endpoint.OAuth2.ClientID, path+".oauth2.clientID", endpoint.Name)
+			resolve(&endpoint.OAuth2.ClientSecret, path+".oauth2.clientSecret", endpoint.Name)
+		}
+		for j := range endpoint.Steps {
+			step := &endpoint.Steps[j]
+			stepPath := fmt.Sprintf("%s.steps[%d]", path, j)
+			for header, value := range step.Headers {
+				resolve(&value, stepPath+".headers."+header, endpoint.Name)
+				step.Headers[header] = value
+			}
+			resolve(&step.Body, stepPath+".body", endpoint.Name)
+		}
+	}
+
+	return problems.err()
+}
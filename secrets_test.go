@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveSecretRefs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("file-secret\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("REST_COMPARE_TEST_TOKEN", "env-secret")
+
+	testCases := []struct {
+		name      string
+		value     string
+		want      string
+		wantError bool
+	}{
+		{"plain value", "Bearer token123", "Bearer token123", false},
+		{"env reference", "Bearer ${env:REST_COMPARE_TEST_TOKEN}", "Bearer env-secret", false},
+		{"relative file reference", "Bearer ${file:token}", "Bearer file-secret", false},
+		{"exec reference", "Basic ${exec:printf 'exec-secret\\n'}", "Basic exec-secret", false},
+		{"multiple references", "${env:REST_COMPARE_TEST_TOKEN}:${file:token}", "env-secret:file-secret", false},
+		{"unset variable", "${env:REST_COMPARE_TEST_MISSING}", "", true},
+		{"missing file", "${file:missing}", "", true},
+		{"failing command", "${exec:exit 1}", "", true},
+		{"unknown type", "${vault:secret/token}", "", true},
+		{"empty reference", "${env:}", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := resolveSecretRefs(tc.value, dir, 5*time.Second)
+			if tc.wantError {
+				if err == nil {
+					t.Errorf("Expected error but got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadConfigRedactsSecrets(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	config := `endpoints:
+  - name: "Production"
+    url: "https://api1.example.com/config"
+    auth: "Bearer ${env:REST_COMPARE_TEST_API_TOKEN}"
+  - name: "Staging"
+    url: "https://api2.example.com/config"
+`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("REST_COMPARE_TEST_API_TOKEN", "s3cr3t-api-token")
+	loaded, err := LoadConfig(path, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if loaded.Endpoints[0].Auth != "Bearer s3cr3t-api-token" {
+		t.Errorf("Unexpected auth: %q", loaded.Endpoints[0].Auth)
+	}
+
+	// Secrets echoed by an endpoint do not appear in reports
+	diff := diffInfo{path: "auth", valueA: "Bearer s3cr3t-api-token", valueB: nil}
+	if text := formatDiff(diff); strings.Contains(text, "s3cr3t-api-token") {
+		t.Errorf("Secret not redacted: %s", text)
+	}
+	report := newJSONReport(loaded.Endpoints[0], loaded.Endpoints[1], comparisonResult{
+		acceptanceResult: acceptanceResult{unaccepted: []diffInfo{diff}},
+	}, nil)
+	if report.Differences[0].A != "Bearer "+redactedSecret {
+		t.Errorf("Secret not redacted in JSON report: %v", report.Differences[0].A)
+	}
+
+	// Unresolvable references fail clearly
+	os.Unsetenv("REST_COMPARE_TEST_API_TOKEN")
+	if _, err := LoadConfig(path, ""); err == nil || !strings.Contains(err.Error(), "REST_COMPARE_TEST_API_TOKEN is not set") {
+		t.Errorf("Expected unset variable error, got %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/theory/jsonpath"
+	"github.com/theory/jsonpath/spec"
+	"gopkg.in/yaml.v3"
+)
+
+// Represents a JSONPath expression selecting a part of the compared documents
+type jsonPathSelector struct {
+	Name string // Groups the differences found in the selected part, empty for a single expression
+	Path string
+}
+
+// Represents the JSONPath selectors of a comparison, given as a single expression,
+// a list of expressions or a map of names to expressions
+type jsonPathSelectors []jsonPathSelector
+
+// Returns the selectors of a single expression, or none if it is empty
+func singleSelector(path string) jsonPathSelectors {
+	if path == "" {
+		return nil
+	}
+	return jsonPathSelectors{{Path: path}}
+}
+
+// Decodes a single expression, a list of expressions (named after themselves)
+// or a map of names to expressions, keeping the order of the document
+func (s *jsonPathSelectors) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		var path string
+		if err := value.Decode(&path); err != nil {
+			return err
+		}
+		*s = singleSelector(path)
+
+	case yaml.SequenceNode:
+		var paths []string
+		if err := value.Decode(&paths); err != nil {
+			return err
+		}
+		*s = nil
+		for _, path := range paths {
+			*s = append(*s, jsonPathSelector{Name: path, Path: path})
+		}
+
+	case yaml.MappingNode:
+		*s = nil
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			var path string
+			if err := value.Content[i+1].Decode(&path); err != nil {
+				return err
+			}
+			*s = append(*s, jsonPathSelector{Name: value.Content[i].Value, Path: path})
+		}
+
+	default:
+		// Reported like other type errors, so that decoding continues
+		return &yaml.TypeError{Errors: []string{fmt.Sprintf("line %d: jsonPath must be an expression, a list or a map of expressions", value.Line)}}
+	}
+	return nil
+}
+
+// Encodes the selectors in the form they were given
+func (s jsonPathSelectors) MarshalYAML() (interface{}, error) {
+	if !s.grouped() {
+		return s.path(), nil
+	}
+
+	named := false
+	for _, selector := range s {
+		named = named || selector.Name != selector.Path
+	}
+	if !named {
+		paths := make([]string, len(s))
+		for i, selector := range s {
+			paths[i] = selector.Path
+		}
+		return paths, nil
+	}
+
+	n := &yaml.Node{Kind: yaml.MappingNode}
+	for _, selector := range s {
+		n.Content = append(n.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: selector.Name},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: selector.Path})
+	}
+	return n, nil
+}
+
+// Reports whether the selected parts are compared as named groups
+func (s jsonPathSelectors) grouped() bool {
+	return len(s) > 1 || (len(s) == 1 && s[0].Name != "")
+}
+
+// Returns the expression of a single selector, or an empty string if there is none
+func (s jsonPathSelectors) path() string {
+	if len(s) == 0 {
+		return ""
+	}
+	return s[0].Path
+}
+
+// Returns the selector names
+func (s jsonPathSelectors) names() []string {
+	names := make([]string, len(s))
+	for i, selector := range s {
+		names[i] = selector.Name
+	}
+	return names
+}
+
+// Formats the selectors for display
+func (s jsonPathSelectors) String() string {
+	if !s.grouped() {
+		return s.path()
+	}
+
+	parts := make([]string, len(s))
+	for i, selector := range s {
+		if selector.Name == selector.Path {
+			parts[i] = selector.Path
+		} else {
+			parts[i] = selector.Name + ": " + selector.Path
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Checks the syntax of the expressions and the uniqueness of the names
+func (s jsonPathSelectors) validate(path string, problems *configProblems) {
+	seen := make(map[string]bool)
+	for i, selector := range s {
+		selectorPath := path
+		switch {
+		case selector.Name == selector.Path:
+			selectorPath = fmt.Sprintf("%s[%d]", path, i)
+		case selector.Name != "":
+			selectorPath = joinConfigPath(path, selector.Name)
+		}
+
+		if seen[selector.Name] {
+			problems.add(selectorPath, "duplicate jsonPath selector: %s", selector.Name)
+		}
+		seen[selector.Name] = true
+
+		if selector.Path == "" {
+			problems.add(selectorPath, "jsonPath selector %s has no expression", selector.Name)
+			continue
+		}
+		if _, err := jsonpath.Parse(selector.Path); err != nil {
+			problems.add(selectorPath, "invalid jsonPath: %v", err)
+		}
+	}
+}
+
+// Returns the names of the selectors of both endpoints, in order and without duplicates
+func selectorNames(selectorsA, selectorsB jsonPathSelectors) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, selectors := range []jsonPathSelectors{selectorsA, selectorsB} {
+		if !selectors.grouped() {
+			continue
+		}
+		for _, name := range selectors.names() {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// Returns an error for a path of a grouped document that does not start with a selector name, and so can never match.
+// List selectors are named after their expressions, which paths split at dots and brackets cannot address.
+func groupPathError(path string, names []string) error {
+	return fmt.Errorf("path %s does not start with a jsonPath selector name (%s); give list selectors names with a map to address them",
+		path, strings.Join(names, ", "))
+}
+
+// Reports whether a field map path, in the format of difference paths, addresses a group of a grouped document
+func fieldMapAddressesGroup(path string, names []string) bool {
+	segments, err := parseDocumentPath(path)
+	if err != nil {
+		return true // Reported by the field map validation
+	}
+	for _, name := range names {
+		if segments[0].key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether a JSONPath expression can select values in a group of a grouped document
+func jsonPathAddressesGroup(expression string, names []string) bool {
+	p, err := jsonpath.Parse(expression)
+	if err != nil {
+		return true // Reported by the expression validation
+	}
+	segments := p.Query().Segments()
+	if len(segments) == 0 || segments[0].IsDescendant() {
+		return true
+	}
+	for _, selector := range segments[0].Selectors() {
+		name, ok := selector.(spec.Name)
+		if !ok {
+			return true // Wildcards and filters may select any group
+		}
+		for _, n := range names {
+			if string(name) == n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Represents the differences found in the part selected by a named selector
+type diffGroup struct {
+	selector string // Empty for differences outside of the selected parts
+	diffs    []diffInfo
+}
+
+// Groups differences by selector, in the order of the selectors
+func groupDiffs(diffs []diffInfo, names []string) []diffGroup {
+	if len(diffs) == 0 {
+		return nil
+	}
+
+	bySelector := make(map[string][]diffInfo)
+	for _, diff := range diffs {
+		name := selectorOf(diff.path, names)
+		bySelector[name] = append(bySelector[name], diff)
+	}
+
+	var groups []diffGroup
+	for _, name := range append([]string{""}, names...) {
+		if len(bySelector[name]) > 0 {
+			groups = append(groups, diffGroup{name, bySelector[name]})
+		}
+	}
+	return groups
+}
+
+// Returns the name of the selector a difference path belongs to, or an empty string
+func selectorOf(path string, names []string) string {
+	found := ""
+	for _, name := range names {
+		if len(name) <= len(found) {
+			continue
+		}
+		if path == name || strings.HasPrefix(path, name+".") || strings.HasPrefix(path, name+"[") {
+			found = name
+		}
+	}
+	return found
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestJSONPathSelectorsYAML(t *testing.T) {
+	testCases := []struct {
+		name    string
+		yaml    string
+		want    jsonPathSelectors
+		grouped bool
+	}{
+		{
+			name: "single expression",
+			yaml: `jsonPath: "$.features"`,
+			want: jsonPathSelectors{{Path: "$.features"}},
+		},
+		{
+			name:    "list of expressions",
+			yaml:    `jsonPath: ["$.database", "$.cache"]`,
+			want:    jsonPathSelectors{{Name: "$.database", Path: "$.database"}, {Name: "$.cache", Path: "$.cache"}},
+			grouped: true,
+		},
+		{
+			name:    "named expressions keep their order",
+			yaml:    "jsonPath:\n  features: \"$.features\"\n  database: \"$.database\"\n",
+			want:    jsonPathSelectors{{Name: "features", Path: "$.features"}, {Name: "database", Path: "$.database"}},
+			grouped: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var settings Settings
+			if err := yaml.Unmarshal([]byte(tc.yaml), &settings); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(settings.JSONPath, tc.want) {
+				t.Errorf("Expected %v, got %v", tc.want, settings.JSONPath)
+			}
+			if settings.JSONPath.grouped() != tc.grouped {
+				t.Errorf("Expected grouped %v", tc.grouped)
+			}
+
+			// Selectors are encoded in the form they were given
+			data, err := yaml.Marshal(settings)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var decoded Settings
+			if err := yaml.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(decoded.JSONPath, tc.want) {
+				t.Errorf("Round trip changed selectors:\n%s", data)
+			}
+		})
+	}
+
+	var settings Settings
+	if err := yaml.Unmarshal([]byte("jsonPath:\n  database: [\"$.database\"]\n"), &settings); err == nil {
+		t.Error("Expected error for a list of expressions in a map")
+	}
+}
+
+func TestValidateSelectors(t *testing.T) {
+	selectors := jsonPathSelectors{
+		{Name: "database", Path: "$.database"},
+		{Name: "cache", Path: "$[bad"},
+		{Name: "database", Path: "$.db"},
+		{Name: "empty"},
+	}
+
+	var problems configProblems
+	selectors.validate("settings.jsonPath", &problems)
+
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.path+": "+p.message)
+	}
+	want := []string{
+		"settings.jsonPath.cache: invalid jsonPath: jsonpath: unexpected identifier at position 3",
+		"settings.jsonPath.database: duplicate jsonPath selector: database",
+		"settings.jsonPath.empty: jsonPath selector empty has no expression",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected problems:\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestExtractSelectors(t *testing.T) {
+	data, _ := parseJSON([]byte(`{"database": {"host": "db"}, "cache": {"ttl": 60}, "items": [{"id": 1}, {"id": 2}]}`))
+	selectors := jsonPathSelectors{
+		{Name: "db", Path: "$.database"},
+		{Name: "cache", Path: "$.cache"},
+	}
+
+	got, err := extractEndpointData(Endpoint{}, data, selectors, jsonPathSingle)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]interface{}{
+		"db":    map[string]interface{}{"host": "db"},
+		"cache": map[string]interface{}{"ttl": float64(60)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// The endpoint field map applies to the grouped document
+	endpoint := Endpoint{FieldMap: map[string]string{"db.host": "db.hostname"}}
+	got, err = extractEndpointData(endpoint, data, selectors, jsonPathSingle)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.(map[string]interface{})["db"].(map[string]interface{})["hostname"] != "db" {
+		t.Errorf("Field map not applied: %v", got)
+	}
+
+	// All results are compared per selector
+	got, err = extractEndpointData(Endpoint{}, data, jsonPathSelectors{{Name: "ids", Path: "$.items[*].id"}}, jsonPathAll)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want = map[string]interface{}{"ids": map[string]interface{}{"$['items'][0]['id']": float64(1), "$['items'][1]['id']": float64(2)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	_, err = extractEndpointData(Endpoint{}, data, jsonPathSelectors{{Name: "missing", Path: "$.missing"}}, jsonPathSingle)
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Expected error naming the selector, got %v", err)
+	}
+}
+
+func TestGroupDiffs(t *testing.T) {
+	diffs := []diffInfo{
+		{"cache.ttl", float64(60), float64(30)},
+		{"database", "[missing]", "object"},
+		{"database.replicas[0]", "a", "b"},
+		{"databases.host", "a", "b"},
+		{"database.host", "a", "b"},
+	}
+
+	groups := groupDiffs(diffs, []string{"database", "cache"})
+	var got []string
+	for _, group := range groups {
+		var paths []string
+		for _, diff := range group.diffs {
+			paths = append(paths, diff.path)
+		}
+		got = append(got, group.selector+": "+strings.Join(paths, ", "))
+	}
+	want := []string{
+		": databases.host",
+		"database: database, database.replicas[0], database.host",
+		"cache: cache.ttl",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected groups:\n got: %q\nwant: %q", got, want)
+	}
+
+	if groupDiffs(nil, []string{"database"}) != nil {
+		t.Error("Expected no groups without differences")
+	}
+}
+
+func TestValidateGroupedPaths(t *testing.T) {
+	newConfig := func(selectors jsonPathSelectors, fieldMap map[string]string, comparators []Comparator) *Config {
+		return &Config{
+			Endpoints: []Endpoint{
+				{Name: "v1", URL: "file://v1.json"},
+				{Name: "v2", URL: "file://v2.json", FieldMap: fieldMap},
+			},
+			Settings: Settings{JSONPath: selectors, Comparators: comparators},
+		}
+	}
+	list := jsonPathSelectors{{Name: "$.database", Path: "$.database"}, {Name: "$.cache", Path: "$.cache"}}
+	named := jsonPathSelectors{{Name: "database", Path: "$.database"}, {Name: "cache", Path: "$.cache"}}
+
+	testCases := []struct {
+		name      string
+		config    *Config
+		wantError string
+	}{
+		{
+			name:      "field map into a list selector",
+			config:    newConfig(list, map[string]string{"$.database.old": "$.database.new"}, nil),
+			wantError: "fieldMap: path $.database.old does not start with a jsonPath selector name ($.database, $.cache)",
+		},
+		{
+			name:   "field map into a named selector",
+			config: newConfig(named, map[string]string{"database.old": "database.new"}, nil),
+		},
+		{
+			name:      "field map outside the selectors",
+			config:    newConfig(named, map[string]string{"old": "database.new"}, nil),
+			wantError: "path old does not start with a jsonPath selector name",
+		},
+		{
+			name:      "comparator into a list selector",
+			config:    newConfig(list, nil, []Comparator{{Path: "$.database.createdAt", Type: comparatorTimestamp}}),
+			wantError: "comparator 1: path $.database.createdAt does not start",
+		},
+		{
+			name: "comparators into named selectors",
+			config: newConfig(named, nil, []Comparator{
+				{Path: "$.database.createdAt", Type: comparatorTimestamp},
+				{Path: "$..timeout", Type: comparatorNumber},
+				{Path: "$['$.database'].x", Type: comparatorNumber},
+				{Path: "$[*].enabled", Type: comparatorBoolean},
+			}),
+			wantError: "comparator 3: path $['$.database'].x does not start",
+		},
+		{
+			name:   "single selector",
+			config: newConfig(singleSelector("$.database"), map[string]string{"old": "new"}, []Comparator{{Path: "$.createdAt", Type: comparatorTimestamp}}),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateConfig(tc.config)
+			if tc.wantError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+				t.Errorf("Expected error containing %q, got %v", tc.wantError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Default settings of the server mode
+const (
+	defaultListenAddr    = ":8080"
+	defaultMaxConcurrent = 4
+	defaultMaxTimeout    = 60 // Seconds
+	maxRequestBodySize   = 1 << 20
+	shutdownTimeout      = 10 * time.Second
+)
+
+// Represents the comparison server
+type server struct {
+	configs map[string]*Config     // Named configurations loaded at startup
+	results map[string]*jsonReport // Last result per configuration
+	mu      sync.Mutex             // Guards results
+	slots   chan struct{}          // Limits concurrent comparisons
+
+	maxTimeout int // Maximum timeout in seconds of ad-hoc comparisons
+}
+
+// Represents the body of an ad-hoc comparison request
+type compareRequest struct {
+	URLA     string                 `json:"urlA"`
+	URLB     string                 `json:"urlB"`
+	AuthA    string                 `json:"authA,omitempty"`
+	AuthB    string                 `json:"authB,omitempty"`
+	Settings compareRequestSettings `json:"settings"`
+}
+
+// Represents the settings of an ad-hoc comparison request
+type compareRequestSettings struct {
+	Timeout     int      `json:"timeout,omitempty"`
+	IgnoredKeys []string `json:"ignoredKeys,omitempty"`
+	JSONPath    string   `json:"jsonPath,omitempty"`
+	Mode        string   `json:"mode,omitempty"`
+}
+
+// Runs the serve subcommand and returns the exit code
+func runServe(args []string) int {
+	fs := newCommandFlagSet("serve", "[config.yaml ...] [--listen :8080] [--max-concurrent 4] [--max-timeout 60]")
+	profile := profileFlag(fs)
+	listen := fs.String("listen", defaultListenAddr, "address to listen on")
+	maxConcurrent := fs.Int("max-concurrent", defaultMaxConcurrent, "maximum number of concurrent comparisons")
+	maxTimeout := fs.Int("max-timeout", defaultMaxTimeout, "maximum timeout in seconds of ad-hoc comparisons")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return flagErrorCode(err)
+	}
+	if *maxConcurrent <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: max-concurrent must be positive")
+		return 2
+	}
+	if *maxTimeout <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: max-timeout must be positive")
+		return 2
+	}
+
+	configs, err := loadNamedConfigs(positional, *profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config file: %v\n", err)
+		return 2
+	}
+
+	srv := &server{
+		configs: configs,
+		results: make(map[string]*jsonReport),
+		slots:   make(chan struct{}, *maxConcurrent),
+
+		maxTimeout: *maxTimeout,
+	}
+	httpServer := &http.Server{
+		Addr:              *listen,
+		Handler:           srv.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("Listening on %s with %d configuration(s)", *listen, len(configs))
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error shutting down: %v\n", err)
+		return 2
+	}
+	return 0
+}
+
+// Loads configuration files with a profile, named after their base name without extension
+func loadNamedConfigs(paths []string, profile string) (map[string]*Config, error) {
+	configs := make(map[string]*Config)
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if _, ok := configs[name]; ok {
+			return nil, errors.New("duplicate configuration name: " + name)
+		}
+
+		config, err := LoadConfig(path, profile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if config.readsStdin() {
+			return nil, fmt.Errorf("%s: standard input can only be read once and cannot be served", path)
+		}
+		configs[name] = config
+	}
+	return configs, nil
+}
+
+// Returns the HTTP handler of the server
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /compare", s.handleAdHocCompare)
+	mux.HandleFunc("POST /compare/{name}", s.handleCompare)
+	mux.HandleFunc("GET /results/{name}", s.handleResults)
+	return mux
+}
+
+// Runs the comparison of a named configuration
+func (s *server) handleCompare(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	config, ok := s.configs[name]
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "unknown configuration: "+name)
+		return
+	}
+
+	report, ok := s.compare(config)
+	if !ok {
+		writeJSONError(w, http.StatusTooManyRequests, "too many concurrent comparisons")
+		return
+	}
+
+	s.mu.Lock()
+	s.results[name] = report
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// Returns the last result of a named configuration
+func (s *server) handleResults(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, ok := s.configs[name]; !ok {
+		writeJSONError(w, http.StatusNotFound, "unknown configuration: "+name)
+		return
+	}
+
+	s.mu.Lock()
+	report, ok := s.results[name]
+	s.mu.Unlock()
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "no result yet for configuration: "+name)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// Runs a comparison described in the request body
+func (s *server) handleAdHocCompare(w http.ResponseWriter, r *http.Request) {
+	var req compareRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	config, err := req.toConfig(s.maxTimeout)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, ok := s.compare(config)
+	if !ok {
+		writeJSONError(w, http.StatusTooManyRequests, "too many concurrent comparisons")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// Compares the endpoints of a configuration if a slot is available
+func (s *server) compare(config *Config) (*jsonReport, bool) {
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		return nil, false
+	}
+	defer func() { <-s.slots }()
+
+	endpointA, endpointB := config.GetDefaultEndpoints()
+	result, err := compareEndpoints(config)
+	return newJSONReport(endpointA, endpointB, result, err), true
+}
+
+// Converts an ad-hoc request into a validated configuration whose timeout does not exceed maxTimeout seconds
+func (req *compareRequest) toConfig(maxTimeout int) (*Config, error) {
+	config := &Config{
+		Endpoints: []Endpoint{
+			{Name: "A", URL: req.URLA, Auth: req.AuthA},
+			{Name: "B", URL: req.URLB, Auth: req.AuthB},
+		},
+		Settings: Settings{
+			Timeout:     req.Settings.Timeout,
+			IgnoredKeys: req.Settings.IgnoredKeys,
+			JSONPath:    singleSelector(req.Settings.JSONPath),
+			Mode:        req.Settings.Mode,
+		},
+	}
+
+	// Local sources must not be reachable through the API
+	for _, endpoint := range config.Endpoints {
+		if !isHTTPSource(endpoint.URL) {
+			return nil, errors.New("only http and https URLs are allowed: " + endpoint.URL)
+		}
+	}
+
+	// Requests must not hold a comparison slot for longer than the server allows
+	if req.Settings.Timeout > maxTimeout {
+		return nil, fmt.Errorf("settings.timeout must not exceed %d seconds", maxTimeout)
+	}
+
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
+	setDefaults(config)
+	config.Settings.Timeout = min(config.Settings.Timeout, maxTimeout)
+
+	return config, nil
+}
+
+// Writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
+
+// Writes a JSON error response
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			w.Write([]byte(`{"timeout":30,"id":"a"}`))
+		case "/b":
+			w.Write([]byte(`{"timeout":60,"id":"b"}`))
+		}
+	}))
+	defer backend.Close()
+
+	config := &Config{
+		Endpoints: []Endpoint{
+			{Name: "Production", URL: backend.URL + "/a"},
+			{Name: "Staging", URL: backend.URL + "/b"},
+		},
+	}
+	setDefaults(config)
+
+	srv := &server{
+		configs: map[string]*Config{"service": config},
+		results: make(map[string]*jsonReport),
+		slots:   make(chan struct{}, 1),
+
+		maxTimeout: 5,
+	}
+	handler := srv.routes()
+
+	do := func(method, path, body string) (*httptest.ResponseRecorder, *jsonReport) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		var report jsonReport
+		json.Unmarshal(rec.Body.Bytes(), &report)
+		return rec, &report
+	}
+
+	if rec, _ := do("GET", "/results/service", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 before any comparison, got %d", rec.Code)
+	}
+
+	rec, report := do("POST", "/compare/service", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if report.Status != statusDifferent || report.ExitCode != 1 || len(report.Differences) != 2 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	rec, stored := do("GET", "/results/service", "")
+	if rec.Code != http.StatusOK || stored.Status != statusDifferent {
+		t.Errorf("Expected stored result, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec, _ := do("POST", "/compare/unknown", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown configuration, got %d", rec.Code)
+	}
+
+	body := `{"urlA":"` + backend.URL + `/a","urlB":"` + backend.URL + `/b","settings":{"ignoredKeys":["timeout","id"]}}`
+	rec, report = do("POST", "/compare", body)
+	if rec.Code != http.StatusOK || report.Status != statusIdentical {
+		t.Errorf("Expected identical ad-hoc result, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	body = `{"urlA":"` + backend.URL + `/a","urlB":"` + backend.URL + `/b","settings":{"timeout":3600}}`
+	if rec, _ := do("POST", "/compare", body); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a timeout above the maximum, got %d", rec.Code)
+	}
+
+	// The default timeout is capped by the maximum as well
+	req := compareRequest{URLA: backend.URL + "/a", URLB: backend.URL + "/b"}
+	if config, err := req.toConfig(5); err != nil || config.GetTimeout() != 5 {
+		t.Errorf("Expected a 5 second timeout, got %+v (%v)", config, err)
+	}
+
+	body = `{"urlA":"file:///etc/passwd","urlB":"` + backend.URL + `/b"}`
+	if rec, _ := do("POST", "/compare", body); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for local source, got %d", rec.Code)
+	}
+
+	// All slots taken
+	srv.slots <- struct{}{}
+	if rec, _ := do("POST", "/compare/service", ""); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 when all slots are taken, got %d", rec.Code)
+	}
+	<-srv.slots
+}
+
+func TestLoadNamedConfigsRejectsStdin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stdin.yaml")
+	if err := os.WriteFile(path, []byte(`endpoints:
+  - {name: A, url: "-"}
+  - {name: B, url: "file:///tmp/b.json"}
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Standard input cannot be read again by later comparisons
+	if _, err := loadNamedConfigs([]string{path}, ""); err == nil || !strings.Contains(err.Error(), "standard input") {
+		t.Errorf("Expected standard input error, got %v", err)
+	}
+}
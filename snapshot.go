@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// Default directory for snapshots
+const defaultSnapshotDir = "snapshots"
+
+// Represents metadata saved alongside a snapshot
+type snapshotMetadata struct {
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	FetchedAt   time.Time `json:"fetchedAt"`
+	StatusCode  int       `json:"statusCode,omitempty"`
+	ContentType string    `json:"contentType,omitempty"`
+}
+
+// Characters not allowed in snapshot file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Runs the snapshot subcommand and returns the exit code
+func runSnapshot(args []string) int {
+	fs := newCommandFlagSet("snapshot", "config.yaml [--out dir] [--allow-errors]")
+	profile := profileFlag(fs)
+	outDir := fs.String("out", defaultSnapshotDir, "directory to save snapshots to")
+	allowErrors := allowErrorsFlag(fs)
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return flagErrorCode(err)
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	config, err := LoadConfig(positional[0], *profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config file: %v\n", err)
+		return 2
+	}
+
+	if err := saveSnapshots(config, *outDir, *allowErrors); err != nil {
+		fmt.Fprintln(os.Stderr, secrets.redact(err.Error()))
+		return 2
+	}
+
+	return 0
+}
+
+// Runs the verify subcommand and returns the exit code
+func runVerify(args []string) int {
+	fs := newCommandFlagSet("verify", "config.yaml [--dir dir] [--update [--allow-errors]]")
+	profile := profileFlag(fs)
+	dir := fs.String("dir", defaultSnapshotDir, "directory containing snapshots")
+	update := fs.Bool("update", false, "refresh snapshots with the live responses")
+	allowErrors := allowErrorsFlag(fs)
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return flagErrorCode(err)
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	config, err := LoadConfig(positional[0], *profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config file: %v\n", err)
+		return 2
+	}
+
+	if *update {
+		if err := saveSnapshots(config, *dir, *allowErrors); err != nil {
+			fmt.Fprintln(os.Stderr, secrets.redact(err.Error()))
+			return 2
+		}
+		return 0
+	}
+
+	drift, err := verifySnapshots(config, *dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, secrets.redact(err.Error()))
+		return 2
+	}
+	if drift {
+		fmt.Println("\nEndpoints drifted from their snapshots.")
+		return 1
+	}
+
+	fmt.Println("Endpoints match their snapshots.")
+	return 0
+}
+
+// Registers the flag allowing non-2xx responses to be saved as snapshots
+func allowErrorsFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("allow-errors", false, "save non-2xx responses as snapshots")
+}
+
+// Fetches every endpoint and saves the raw responses with metadata.
+// Non-2xx responses are rejected unless allowErrors is set, so that an error is not recorded as the expected response.
+func saveSnapshots(config *Config, dir string, allowErrors bool) error {
+	if err := checkSnapshotNames(config.Endpoints); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("Error creating snapshot directory: %v", err)
+	}
+
+	timeout := time.Duration(config.GetTimeout()) * time.Second
+	for _, endpoint := range config.Endpoints {
+		resp, err := fetchRaw(endpoint, timeout)
+		if err != nil {
+			return fmt.Errorf("Error fetching from endpoint %s: %v", endpoint.Name, err)
+		}
+
+		if !allowErrors && (resp.statusCode < 200 || resp.statusCode > 299) {
+			return fmt.Errorf("Error fetching from endpoint %s: status %d (use --allow-errors to save it as a snapshot)", endpoint.Name, resp.statusCode)
+		}
+
+		// Only valid JSON is useful as a snapshot
+		if _, err := parseJSON(resp.body); err != nil {
+			return fmt.Errorf("Error parsing response from endpoint %s: %v", endpoint.Name, err)
+		}
+
+		metadata := snapshotMetadata{
+			Name:        endpoint.Name,
+			URL:         endpoint.URL,
+			FetchedAt:   time.Now().UTC(),
+			StatusCode:  resp.statusCode,
+			ContentType: resp.contentType,
+		}
+		if err := writeSnapshot(dir, metadata, resp.body); err != nil {
+			return fmt.Errorf("Error saving snapshot for endpoint %s: %v", endpoint.Name, err)
+		}
+
+		fmt.Printf("Saved snapshot: %s (%s)\n", endpoint.Name, snapshotBodyPath(dir, endpoint.Name))
+	}
+
+	return nil
+}
+
+// Compares every endpoint with its snapshot and reports whether any drifted
+func verifySnapshots(config *Config, dir string) (bool, error) {
+	if err := checkSnapshotNames(config.Endpoints); err != nil {
+		return false, err
+	}
+
+	opts := newCompareOptions(config)
+	timeout := time.Duration(config.GetTimeout()) * time.Second
+
+	drift := false
+	for _, endpoint := range config.Endpoints {
+		snapshot, err := readSnapshot(dir, endpoint.Name)
+		if err != nil {
+			return false, fmt.Errorf("Error reading snapshot for endpoint %s: %v", endpoint.Name, err)
+		}
+
+		live, err := fetchJSON(endpoint, timeout)
+		if err != nil {
+			return false, fmt.Errorf("Error fetching from endpoint %s: %v", endpoint.Name, err)
+		}
+
+		dataSnapshot, dataLive, err := processJSONData(endpoint, endpoint, snapshot, live, config.GetJSONPathSelectors(), config.GetJSONPathResults())
+		if err != nil {
+			return false, fmt.Errorf("Error processing endpoint %s: %v", endpoint.Name, err)
+		}
+		dataSnapshot, dataLive, err = transformDocuments(dataSnapshot, dataLive, config.GetTransforms())
+		if err != nil {
+			return false, fmt.Errorf("Error processing endpoint %s: %v", endpoint.Name, err)
+		}
+
+		equal, diffs := compareJSONWithOptions(dataSnapshot, dataLive, opts)
+		if equal {
+			fmt.Printf("%s: matches snapshot\n", endpoint.Name)
+			continue
+		}
+
+		drift = true
+		fmt.Printf("%s: difference found (A: snapshot, B: live):\n", endpoint.Name)
+		for _, diff := range diffs {
+			fmt.Println(diff)
+		}
+	}
+
+	return drift, nil
+}
+
+// Writes the snapshot body and metadata files
+func writeSnapshot(dir string, metadata snapshotMetadata, body []byte) error {
+	metadataJSON, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(snapshotBodyPath(dir, metadata.Name), body, 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(snapshotMetadataPath(dir, metadata.Name), append(metadataJSON, '\n'), 0o644)
+}
+
+// Reads and parses a saved snapshot body
+func readSnapshot(dir, name string) (interface{}, error) {
+	body, err := os.ReadFile(snapshotBodyPath(dir, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no snapshot found in %s, run snapshot first", dir)
+		}
+		return nil, err
+	}
+	return parseJSON(body)
+}
+
+// Returns the path of the snapshot body for an endpoint
+func snapshotBodyPath(dir, name string) string {
+	return filepath.Join(dir, snapshotFileName(name)+".json")
+}
+
+// Returns the path of the snapshot metadata for an endpoint
+func snapshotMetadataPath(dir, name string) string {
+	return filepath.Join(dir, snapshotFileName(name)+".meta.json")
+}
+
+// Converts an endpoint name to a safe file name
+func snapshotFileName(name string) string {
+	return unsafeFileChars.ReplaceAllString(name, "_")
+}
+
+// Checks that no two endpoints share a snapshot file
+func checkSnapshotNames(endpoints []Endpoint) error {
+	seen := make(map[string]string)
+	for _, endpoint := range endpoints {
+		fileName := snapshotFileName(endpoint.Name)
+		if other, ok := seen[fileName]; ok {
+			return fmt.Errorf("endpoints %s and %s map to the same snapshot file %s", other, endpoint.Name, fileName)
+		}
+		seen[fileName] = endpoint.Name
+	}
+	return nil
+}
+
+// Parses flags that may appear before or after positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
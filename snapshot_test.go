@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	body := `{"name":"api","timeout":30}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	config := &Config{
+		Endpoints: []Endpoint{
+			{Name: "Production", URL: server.URL},
+			{Name: "Staging env", URL: server.URL},
+		},
+	}
+	setDefaults(config)
+	dir := t.TempDir()
+
+	if err := saveSnapshots(config, dir, false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(snapshotMetadataPath(dir, "Staging env")); err != nil {
+		t.Errorf("Expected metadata file: %v", err)
+	}
+
+	drift, err := verifySnapshots(config, dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if drift {
+		t.Error("Expected no drift right after taking snapshots")
+	}
+
+	body = `{"name":"api","timeout":60}`
+	drift, err = verifySnapshots(config, dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !drift {
+		t.Error("Expected drift after the response changed")
+	}
+}
+
+func TestSnapshotRejectsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"database unavailable"}`))
+	}))
+	defer server.Close()
+
+	config := &Config{Endpoints: []Endpoint{{Name: "Production", URL: server.URL}}}
+	setDefaults(config)
+	dir := t.TempDir()
+
+	// An error response is not recorded as the expected response
+	if err := saveSnapshots(config, dir, false); err == nil {
+		t.Fatal("Expected error for a 500 response")
+	}
+	if _, err := os.Stat(snapshotBodyPath(dir, "Production")); !os.IsNotExist(err) {
+		t.Errorf("Expected no snapshot, got %v", err)
+	}
+
+	// Unless explicitly allowed
+	if err := saveSnapshots(config, dir, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(snapshotBodyPath(dir, "Production")); err != nil {
+		t.Errorf("Expected snapshot: %v", err)
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	out := fs.String("out", "", "")
+	update := fs.Bool("update", false, "")
+
+	positional, err := parseInterspersed(fs, []string{"config.yaml", "--out", "dir", "extra", "--update"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(positional, []string{"config.yaml", "extra"}) {
+		t.Errorf("Unexpected positional arguments: %v", positional)
+	}
+	if *out != "dir" || !*update {
+		t.Errorf("Flags not parsed: out=%q update=%v", *out, *update)
+	}
+}
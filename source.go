@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Endpoint sources other than HTTP URLs
+const (
+	stdinSource = "-"       // Read JSON from standard input
+	fileScheme  = "file://" // Read JSON from a local file
+	execPrefix  = "exec:"   // Read JSON from the output of a command
+)
+
+// Supported HTTP URL schemes
+const (
+	httpScheme  = "http://"
+	httpsScheme = "https://"
+)
+
+// Shell used to run exec sources
+const (
+	execShell    = "sh"
+	execShellArg = "-c"
+)
+
+// Fetches JSON data from the endpoint source and converts it to a value
+func fetchJSON(endpoint Endpoint, timeout time.Duration) (interface{}, error) {
+	resp, err := fetchRaw(endpoint, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse JSON
+	return parseJSON(resp.body)
+}
+
+// Fetches the raw data from the endpoint source
+func fetchRaw(endpoint Endpoint, timeout time.Duration) (*rawResponse, error) {
+	source := endpoint.URL
+	switch {
+	case source == stdinSource:
+		return readStdin()
+	case strings.HasPrefix(source, fileScheme):
+		return readFile(strings.TrimPrefix(source, fileScheme))
+	case strings.HasPrefix(source, execPrefix):
+		return runCommand(strings.TrimPrefix(source, execPrefix), timeout)
+	case len(endpoint.Steps) > 0:
+		return runSteps(endpoint, timeout)
+	case endpoint.Pagination != nil:
+		return fetchPaginated(endpoint, timeout)
+	default:
+		return fetchHTTP(endpoint, source, timeout)
+	}
+}
+
+// Reads data from standard input
+func readStdin() (*rawResponse, error) {
+	body, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	return &rawResponse{body: body}, nil
+}
+
+// Reads data from a local file
+func readFile(path string) (*rawResponse, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &rawResponse{body: body}, nil
+}
+
+// Runs a shell command and reads its standard output
+func runCommand(command string, timeout time.Duration) (*rawResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, execShell, execShellArg, command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("command timed out after %s: %s", timeout, command)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("command failed: %v: %s", err, msg)
+		}
+		return nil, fmt.Errorf("command failed: %v", err)
+	}
+
+	return &rawResponse{body: stdout.Bytes()}, nil
+}
+
+// Validates the source of an endpoint
+func validateSource(source string) error {
+	switch {
+	case source == stdinSource:
+		return nil
+	case strings.HasPrefix(source, fileScheme):
+		if strings.TrimPrefix(source, fileScheme) == "" {
+			return errors.New("file path is required: " + source)
+		}
+		return nil
+	case strings.HasPrefix(source, execPrefix):
+		if strings.TrimSpace(strings.TrimPrefix(source, execPrefix)) == "" {
+			return errors.New("command is required: " + source)
+		}
+		return nil
+	case isHTTPSource(source):
+		u, err := url.Parse(source)
+		if err != nil {
+			return fmt.Errorf("invalid URL: %w", err)
+		}
+		if u.Host == "" {
+			return errors.New("URL has no host: " + source)
+		}
+		return nil
+	default:
+		return errors.New("unsupported endpoint source: " + source)
+	}
+}
+
+// Reports whether the source is an HTTP URL
+func isHTTPSource(source string) bool {
+	return strings.HasPrefix(source, httpScheme) || strings.HasPrefix(source, httpsScheme)
+}
+
+// Resolves a relative file source against the given base directory
+func resolveSource(baseDir, source string) string {
+	if !strings.HasPrefix(source, fileScheme) {
+		return source
+	}
+	return fileScheme + resolvePath(baseDir, strings.TrimPrefix(source, fileScheme))
+}
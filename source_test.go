@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFetchJSONSources(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"timeout":30}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name      string
+		source    string
+		wantError bool
+	}{
+		{"file source", fileScheme + path, false},
+		{"missing file", fileScheme + filepath.Join(dir, "missing.json"), true},
+		{"exec source", execPrefix + "echo '{\"timeout\":30}'", false},
+		{"failing command", execPrefix + "exit 1", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := fetchJSON(Endpoint{Name: tc.name, URL: tc.source}, 5*time.Second)
+			if tc.wantError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if data.(map[string]interface{})["timeout"] != float64(30) {
+				t.Errorf("Unexpected data: %v", data)
+			}
+		})
+	}
+}
+
+func TestValidateSource(t *testing.T) {
+	valid := []string{"https://api.example.com/config", "http://localhost:8080", "-", "file:///tmp/a.json", "exec:kubectl get cm -o json"}
+	for _, source := range valid {
+		if err := validateSource(source); err != nil {
+			t.Errorf("validateSource(%q) returned error: %v", source, err)
+		}
+	}
+
+	invalid := []string{"ftp://example.com", "file://", "exec: ", "api.example.com/config"}
+	for _, source := range invalid {
+		if err := validateSource(source); err == nil {
+			t.Errorf("validateSource(%q) expected error", source)
+		}
+	}
+}
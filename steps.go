@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/theory/jsonpath"
+)
+
+// Matches {{var}} placeholders in step templates
+var templateVar = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// Represents a request in a chain whose last response is compared
+type Step struct {
+	Method  string            `yaml:"method,omitempty"`  // Default: GET
+	URL     string            `yaml:"url"`               // Absolute or relative to the endpoint URL
+	Headers map[string]string `yaml:"headers,omitempty"` // Supports {{var}} templating
+	Body    string            `yaml:"body,omitempty"`    // Supports {{var}} templating
+	Capture map[string]string `yaml:"capture,omitempty"` // Variable name to JSONPath in the response
+}
+
+// Validates the steps, checking that every variable is captured before it is used
+func validateSteps(steps []Step) error {
+	captured := make(map[string]bool)
+
+	for i, step := range steps {
+		if step.URL == "" {
+			return fmt.Errorf("step %d: url is required", i+1)
+		}
+
+		templates := []string{step.URL, step.Body}
+		for _, value := range step.Headers {
+			templates = append(templates, value)
+		}
+		for _, template := range templates {
+			for _, match := range templateVar.FindAllStringSubmatch(template, -1) {
+				if !captured[match[1]] {
+					return fmt.Errorf("step %d: variable %s is not captured by a previous step", i+1, match[1])
+				}
+			}
+		}
+
+		for name, path := range step.Capture {
+			if _, err := jsonpath.Parse(path); err != nil {
+				return fmt.Errorf("step %d: invalid JSONPath for %s: %w", i+1, name, err)
+			}
+			captured[name] = true
+		}
+	}
+
+	return nil
+}
+
+// Runs the steps of an endpoint and returns the response of the last step
+func runSteps(endpoint Endpoint, timeout time.Duration) (*rawResponse, error) {
+	vars := make(map[string]string)
+
+	var resp *rawResponse
+	for i, step := range endpoint.Steps {
+		request, err := renderStep(endpoint, step, vars)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+
+		resp, err = sendWithAuth(endpoint, request, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+
+		// Intermediate steps must succeed for later steps to make sense
+		last := i == len(endpoint.Steps)-1
+		if !last && (resp.statusCode < 200 || resp.statusCode > 299) {
+			return nil, fmt.Errorf("step %d: %s %s returned status %d", i+1, request.method, request.url, resp.statusCode)
+		}
+
+		if len(step.Capture) == 0 {
+			continue
+		}
+		if err := captureVars(resp.body, step.Capture, vars); err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+
+	return resp, nil
+}
+
+// Captures variables from a response body
+func captureVars(body []byte, capture map[string]string, vars map[string]string) error {
+	data, err := parseJSON(body)
+	if err != nil {
+		return err
+	}
+
+	// Capture in a stable order so errors are deterministic
+	var names []string
+	for name := range capture {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value, err := extractPath(data, capture[name])
+		if err != nil {
+			return fmt.Errorf("capturing %s: %w", name, err)
+		}
+		if value == nil {
+			return errors.New("capturing " + name + ": value is null")
+		}
+		vars[name] = valueText(value)
+	}
+	return nil
+}
+
+// Builds the request of a step, substituting captured variables
+func renderStep(endpoint Endpoint, step Step, vars map[string]string) (httpRequest, error) {
+	request := httpRequest{method: step.Method, headers: make(map[string]string)}
+	if request.method == "" {
+		request.method = http.MethodGet
+	}
+
+	stepURL, err := renderTemplate(step.URL, vars, urlEscaper(step.URL))
+	if err != nil {
+		return request, fmt.Errorf("url: %w", err)
+	}
+	request.url = resolveURL(endpoint.URL, stepURL)
+
+	if step.Body != "" {
+		body, err := renderTemplate(step.Body, vars, bodyEscaper(endpoint, step))
+		if err != nil {
+			return request, fmt.Errorf("body: %w", err)
+		}
+		request.body = []byte(body)
+	}
+
+	for name, value := range step.Headers {
+		header, err := renderTemplate(value, vars, escapeHeaderValue)
+		if err != nil {
+			return request, fmt.Errorf("header %s: %w", name, err)
+		}
+		request.headers[name] = header
+	}
+	return request, nil
+}
+
+// Substitutes {{var}} placeholders with captured values, escaped for the context of each placeholder
+func renderTemplate(template string, vars map[string]string, escape func(value string, offset int) (string, error)) (string, error) {
+	var b strings.Builder
+	last := 0
+	for _, match := range templateVar.FindAllStringSubmatchIndex(template, -1) {
+		name := template[match[2]:match[3]]
+		value, ok := vars[name]
+		if !ok {
+			return "", fmt.Errorf("variable %s is not defined", name)
+		}
+		escaped, err := escape(value, match[0])
+		if err != nil {
+			return "", fmt.Errorf("variable %s: %w", name, err)
+		}
+		b.WriteString(template[last:match[0]])
+		b.WriteString(escaped)
+		last = match[1]
+	}
+	b.WriteString(template[last:])
+	return b.String(), nil
+}
+
+// Returns an escaper for a URL template: values in the query or fragment are query escaped
+// and values in the path are path escaped. A value starting the URL is its base and is kept as is.
+func urlEscaper(template string) func(string, int) (string, error) {
+	query := strings.IndexAny(template, "?#")
+	return func(value string, offset int) (string, error) {
+		switch {
+		case offset == 0:
+			return value, nil
+		case query >= 0 && offset > query:
+			return url.QueryEscape(value), nil
+		default:
+			return url.PathEscape(value), nil
+		}
+	}
+}
+
+// Returns an escaper for a body template, chosen by the Content-Type of the step or the endpoint:
+// values are form encoded in form bodies, JSON string escaped in JSON bodies (the default) and kept as is otherwise
+func bodyEscaper(endpoint Endpoint, step Step) func(string, int) (string, error) {
+	contentType, ok := headerValue(step.Headers, "Content-Type")
+	if !ok {
+		contentType, ok = headerValue(endpoint.Headers, "Content-Type")
+	}
+	if !ok {
+		return escapeJSONString
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	switch {
+	case err != nil:
+		return escapeRaw
+	case mediaType == "application/x-www-form-urlencoded":
+		return escapeFormValue
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return escapeJSONString
+	default:
+		return escapeRaw
+	}
+}
+
+// Escapes a value as a form field name or value
+func escapeFormValue(value string, _ int) (string, error) {
+	return url.QueryEscape(value), nil
+}
+
+// Keeps a value as is
+func escapeRaw(value string, _ int) (string, error) {
+	return value, nil
+}
+
+// Escapes a value as the content of a JSON string
+func escapeJSONString(value string, _ int) (string, error) {
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	encoded := strings.TrimSuffix(b.String(), "\n")
+	return encoded[1 : len(encoded)-1], nil
+}
+
+// Rejects values that would split a header
+func escapeHeaderValue(value string, _ int) (string, error) {
+	if strings.ContainsAny(value, "\r\n") {
+		return "", errors.New("value contains a line break")
+	}
+	return value, nil
+}
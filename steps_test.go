@@ -0,0 +1,138 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRunSteps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/login":
+			body, _ := io.ReadAll(r.Body)
+			if r.Method != http.MethodPost || string(body) != `{"user":"admin"}` {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"token":"secret"}`))
+		case "/api/users":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"items":[{"id":42}]}`))
+		case "/api/users/42":
+			w.Write([]byte(`{"id":42,"name":"alice"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	steps := []Step{
+		{Method: "POST", URL: "login", Body: `{"user":"admin"}`, Capture: map[string]string{"token": "$.token"}},
+		{URL: "/api/users", Headers: map[string]string{"Authorization": "Bearer {{token}}"}, Capture: map[string]string{"userId": "$.items[0].id"}},
+		{URL: "/api/users/{{ userId }}"},
+	}
+	if err := validateSteps(steps); err != nil {
+		t.Fatalf("Unexpected validation error: %v", err)
+	}
+
+	endpoint := Endpoint{Name: "Production", URL: server.URL + "/api/", Steps: steps}
+	data, err := fetchJSON(endpoint, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data.(map[string]interface{})["name"] != "alice" {
+		t.Errorf("Unexpected final response: %v", data)
+	}
+
+	// A failing intermediate step stops the chain
+	endpoint.Steps = []Step{{URL: "/api/users", Capture: map[string]string{"userId": "$.items[0].id"}}, {URL: "/api/users/{{userId}}"}}
+	if _, err := fetchJSON(endpoint, 5*time.Second); err == nil {
+		t.Error("Expected error for unauthorized intermediate step")
+	}
+}
+
+func TestValidateSteps(t *testing.T) {
+	testCases := []struct {
+		name  string
+		steps []Step
+	}{
+		{"missing url", []Step{{}}},
+		{"variable used before capture", []Step{{URL: "/users/{{id}}", Capture: map[string]string{"id": "$.id"}}}},
+		{"unknown variable in header", []Step{{URL: "/a", Headers: map[string]string{"X-Token": "{{token}}"}}}},
+		{"invalid JSONPath", []Step{{URL: "/a", Capture: map[string]string{"id": "$[invalid"}}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateSteps(tc.steps); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
+
+func TestRenderStep(t *testing.T) {
+	vars := map[string]string{"q": "a&b=c", "id": "x/y z", "quote": `x"y`, "next": "https://api.example.com/items?page=2"}
+	endpoint := Endpoint{URL: "https://api.example.com/"}
+
+	request, err := renderStep(endpoint, Step{
+		URL:     "users/{{id}}?q={{q}}",
+		Headers: map[string]string{"X-Query": "{{q}}"},
+		Body:    `{"name": "{{quote}}", "q": "{{q}}"}`,
+	}, vars)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if request.url != "https://api.example.com/users/x%2Fy%20z?q=a%26b%3Dc" {
+		t.Errorf("Unexpected URL: %s", request.url)
+	}
+	if string(request.body) != `{"name": "x\"y", "q": "a&b=c"}` {
+		t.Errorf("Unexpected body: %s", request.body)
+	}
+	if request.headers["X-Query"] != "a&b=c" {
+		t.Errorf("Unexpected header: %s", request.headers["X-Query"])
+	}
+
+	// Form bodies are form encoded and other bodies are kept as is
+	request, err = renderStep(endpoint, Step{
+		URL:     "login",
+		Headers: map[string]string{"content-type": "application/x-www-form-urlencoded; charset=utf-8"},
+		Body:    "q={{q}}&name={{quote}}",
+	}, vars)
+	if err != nil || string(request.body) != "q=a%26b%3Dc&name=x%22y" {
+		t.Errorf("Unexpected form body: %s (%v)", request.body, err)
+	}
+	request, err = renderStep(Endpoint{URL: endpoint.URL, Headers: map[string]string{"Content-Type": "text/plain"}},
+		Step{URL: "notes", Body: "{{quote}} {{q}}"}, vars)
+	if err != nil || string(request.body) != `x"y a&b=c` {
+		t.Errorf("Unexpected plain body: %s (%v)", request.body, err)
+	}
+	request, err = renderStep(endpoint, Step{
+		URL:     "users",
+		Headers: map[string]string{"Content-Type": "application/merge-patch+json"},
+		Body:    `{"name": "{{quote}}"}`,
+	}, vars)
+	if err != nil || string(request.body) != `{"name": "x\"y"}` {
+		t.Errorf("Unexpected JSON body: %s (%v)", request.body, err)
+	}
+
+	// A captured link is followed as is
+	if request, err := renderStep(endpoint, Step{URL: "{{next}}"}, vars); err != nil || request.url != vars["next"] {
+		t.Errorf("Unexpected URL: %s (%v)", request.url, err)
+	}
+
+	// Undefined variables and header line breaks are errors
+	if _, err := renderStep(endpoint, Step{URL: "users/{{missing}}"}, vars); err == nil {
+		t.Error("Expected error for an undefined variable")
+	}
+	vars["token"] = "a\r\nX-Injected: 1"
+	if _, err := renderStep(endpoint, Step{URL: "users", Headers: map[string]string{"Authorization": "Bearer {{token}}"}}, vars); err == nil {
+		t.Error("Expected error for a header value with a line break")
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Represents the name of a JSON type in structure comparisons
+type jsonTypeName string
+
+// Represents an array element position holding values of several JSON types
+type mixedTypes []string
+
+// Returns the JSON type name of a value
+func jsonType(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float32, float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case mixedTypes:
+		return strings.Join(val, "|")
+	default:
+		return "unknown"
+	}
+}
+
+// Returns the value to report for a field present on only one side
+func describeValue(v interface{}, opts *compareOptions) interface{} {
+	if opts.structureOnly() {
+		return jsonTypeName(jsonType(v))
+	}
+	return v
+}
+
+// Recursively finds structural differences (missing fields and type changes) between two objects
+func findStructureDiffPaths(a, b interface{}, currentPath string, opts *compareOptions) []diffInfo {
+	// Check for type differences
+	typeA, typeB := jsonType(a), jsonType(b)
+	if typeA != typeB {
+		return []diffInfo{{currentPath, jsonTypeName(typeA), jsonTypeName(typeB)}}
+	}
+
+	// Handle maps
+	mapA, okA := a.(map[string]interface{})
+	mapB, okB := b.(map[string]interface{})
+	if okA && okB {
+		return findMapDifferences(mapA, mapB, currentPath, opts)
+	}
+
+	// Handle slices by comparing the union of their element shapes
+	sliceA, okA := a.([]interface{})
+	sliceB, okB := b.([]interface{})
+	if okA && okB {
+		// The element shape of an empty array is unknown
+		if len(sliceA) == 0 || len(sliceB) == 0 {
+			return nil
+		}
+		return findDiffPaths(mergeShapes(sliceA), mergeShapes(sliceB), currentPath+"[*]", opts)
+	}
+
+	// Primitive values of the same type have the same structure
+	return nil
+}
+
+// Merges array elements into a single value describing the shape of every element
+func mergeShapes(values []interface{}) interface{} {
+	types := make(map[string]bool)
+	for _, v := range values {
+		types[jsonType(v)] = true
+	}
+
+	// Elements of different types are reported as a type union
+	if len(types) > 1 {
+		var names []string
+		for name := range types {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return mixedTypes(names)
+	}
+
+	switch values[0].(type) {
+	case map[string]interface{}:
+		// Collect the values of each key across all objects
+		fields := make(map[string][]interface{})
+		for _, v := range values {
+			for k, fv := range v.(map[string]interface{}) {
+				fields[k] = append(fields[k], fv)
+			}
+		}
+		merged := make(map[string]interface{}, len(fields))
+		for k, fvs := range fields {
+			merged[k] = mergeShapes(fvs)
+		}
+		return merged
+	case []interface{}:
+		// Flatten nested arrays into a single element list
+		var elements []interface{}
+		for _, v := range values {
+			elements = append(elements, v.([]interface{})...)
+		}
+		if len(elements) == 0 {
+			return []interface{}{}
+		}
+		return []interface{}{mergeShapes(elements)}
+	default:
+		return values[0]
+	}
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/theory/jsonpath"
+	"github.com/theory/jsonpath/spec"
+)
+
+// Encodings tried when decoding base64 encoded JSON
+var base64Encodings = []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding}
+
+// Transform operations
+const (
+	transformSort       = "sort"       // Sorts arrays by a field of their elements, or by the elements themselves
+	transformDropNulls  = "dropNulls"  // Removes object members whose value is null
+	transformUnwrap     = "unwrap"     // Replaces an envelope object by the value of one of its fields
+	transformMapToArray = "mapToArray" // Converts an object into an array of its values, ordered by key
+	transformLowercase  = "lowercase"  // Lowercases strings
+	transformDecodeJSON = "decodeJSON" // Replaces strings holding JSON (or base64 encoded JSON) by their content
+)
+
+// Represents an operation reshaping both documents before they are compared
+type Transform struct {
+	Op    string `yaml:"op"`
+	Path  string `yaml:"path,omitempty"`  // JSONPath selecting the values to transform (default: $)
+	Field string `yaml:"field,omitempty"` // Field used by sort, unwrap and mapToArray
+
+	Base64 bool `yaml:"base64,omitempty"` // Whether decodeJSON also decodes base64 encoded JSON
+}
+
+// Checks the operation, its JSONPath and its field
+func (t *Transform) validate() error {
+	switch t.Op {
+	case "":
+		return errors.New("op is required")
+	case transformSort, transformDropNulls, transformUnwrap, transformMapToArray, transformLowercase, transformDecodeJSON:
+	default:
+		return fmt.Errorf("unknown op: %s", t.Op)
+	}
+
+	if _, err := jsonpath.Parse(t.selector()); err != nil {
+		return fmt.Errorf("invalid path: %v", err)
+	}
+
+	switch t.Op {
+	case transformUnwrap:
+		if t.Field == "" {
+			return errors.New("unwrap requires a field")
+		}
+		fallthrough
+	case transformSort:
+		if t.Field != "" {
+			if _, err := parseDocumentPath(t.Field); err != nil {
+				return fmt.Errorf("invalid field: %v", err)
+			}
+		}
+	case transformDropNulls, transformLowercase, transformDecodeJSON:
+		if t.Field != "" {
+			return fmt.Errorf("%s does not use a field", t.Op)
+		}
+	}
+	if t.Base64 && t.Op != transformDecodeJSON {
+		return fmt.Errorf("base64 is only used by %s", transformDecodeJSON)
+	}
+	return nil
+}
+
+// Returns the JSONPath selecting the values to transform
+func (t *Transform) selector() string {
+	if t.Path == "" {
+		return "$"
+	}
+	return t.Path
+}
+
+// Describes the transform for display
+func (t *Transform) String() string {
+	description := t.Op + " " + t.selector()
+	if t.Field != "" {
+		description += " by " + t.Field
+	}
+	if t.Base64 {
+		description += " (including base64)"
+	}
+	return description
+}
+
+// Applies the transforms in order to a copy of a document
+func applyTransforms(data interface{}, transforms []Transform) (interface{}, error) {
+	if len(transforms) == 0 {
+		return data, nil
+	}
+
+	data = copyJSONValue(data)
+	for i, t := range transforms {
+		var err error
+		if data, err = t.apply(data); err != nil {
+			return nil, fmt.Errorf("transform %d (%s): %w", i+1, t.Op, err)
+		}
+	}
+	return data, nil
+}
+
+// Applies the transform to every selected value, innermost values first
+// so that transforming a value does not move the values selected inside it
+func (t *Transform) apply(data interface{}) (interface{}, error) {
+	p, err := jsonpath.Parse(t.selector())
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := p.SelectLocated(data).Deduplicate()
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return len(nodes[i].Path) > len(nodes[j].Path)
+	})
+
+	for _, node := range nodes {
+		value, err := t.transform(node.Node)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", node.Path, err)
+		}
+		data = replaceDocumentValue(data, locatedSegments(node.Path), value)
+	}
+	return data, nil
+}
+
+// Returns the transformed value. Values the operation does not apply to are returned unchanged,
+// so that transforms can reshape a document that only one of the endpoints returns in that shape.
+func (t *Transform) transform(value interface{}) (interface{}, error) {
+	switch t.Op {
+	case transformSort:
+		array, ok := value.([]interface{})
+		if !ok {
+			return value, nil
+		}
+		return sortArray(array, t.Field)
+
+	case transformDropNulls:
+		return dropNulls(value), nil
+
+	case transformUnwrap:
+		segments, err := parseDocumentPath(t.Field)
+		if err != nil {
+			return nil, err
+		}
+		unwrapped, ok := documentValue(value, segments)
+		if !ok {
+			return value, nil
+		}
+		return unwrapped, nil
+
+	case transformMapToArray:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value, nil
+		}
+		return mapToArray(object, t.Field)
+
+	case transformLowercase:
+		return lowercaseStrings(value), nil
+
+	case transformDecodeJSON:
+		return decodeEmbeddedJSON(value, t.Base64), nil
+	}
+	return nil, fmt.Errorf("unknown op: %s", t.Op)
+}
+
+// Sorts the elements of an array by a field, or by the elements themselves if the field is empty
+func sortArray(array []interface{}, field string) ([]interface{}, error) {
+	var segments []pathSegment
+	if field != "" {
+		var err error
+		if segments, err = parseDocumentPath(field); err != nil {
+			return nil, err
+		}
+	}
+
+	key := func(element interface{}) interface{} {
+		if len(segments) == 0 {
+			return element
+		}
+		value, _ := documentValue(element, segments)
+		return value
+	}
+
+	sorted := append([]interface{}(nil), array...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareJSONValues(key(sorted[i]), key(sorted[j])) < 0
+	})
+	return sorted, nil
+}
+
+// Orders JSON values: null and missing values, booleans, numbers, strings, then other values by their encoding
+func compareJSONValues(a, b interface{}) int {
+	rank := func(v interface{}) int {
+		switch v.(type) {
+		case nil:
+			return 0
+		case bool:
+			return 1
+		case float64:
+			return 2
+		case string:
+			return 3
+		default:
+			return 4
+		}
+	}
+	if rankA, rankB := rank(a), rank(b); rankA != rankB {
+		return rankA - rankB
+	}
+
+	switch valueA := a.(type) {
+	case bool:
+		valueB := b.(bool)
+		switch {
+		case valueA == valueB:
+			return 0
+		case !valueA:
+			return -1
+		default:
+			return 1
+		}
+	case float64:
+		valueB := b.(float64)
+		switch {
+		case valueA < valueB:
+			return -1
+		case valueA > valueB:
+			return 1
+		default:
+			return 0
+		}
+	case string:
+		return strings.Compare(valueA, b.(string))
+	case nil:
+		return 0
+	default:
+		encodedA, _ := json.Marshal(a)
+		encodedB, _ := json.Marshal(b)
+		return strings.Compare(string(encodedA), string(encodedB))
+	}
+}
+
+// Removes object members whose value is null, at any depth
+func dropNulls(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if item == nil {
+				delete(v, key)
+				continue
+			}
+			v[key] = dropNulls(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = dropNulls(item)
+		}
+	}
+	return value
+}
+
+// Converts an object into an array of its values ordered by key.
+// With a field, the values must be objects and receive their key in that field.
+func mapToArray(object map[string]interface{}, field string) ([]interface{}, error) {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	array := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		value := object[key]
+		if field != "" {
+			element, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("value of %s is not an object", key)
+			}
+			if _, exists := element[field]; exists {
+				return nil, fmt.Errorf("value of %s already has a field %s", key, field)
+			}
+			element[field] = key
+		}
+		array = append(array, value)
+	}
+	return array, nil
+}
+
+// Lowercases strings, at any depth
+func lowercaseStrings(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return strings.ToLower(v)
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = lowercaseStrings(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = lowercaseStrings(item)
+		}
+	}
+	return value
+}
+
+// Replaces strings holding a JSON object or array, at any depth, by their decoded content,
+// which is decoded in turn. With base64, strings holding base64 encoded JSON are decoded as well.
+func decodeEmbeddedJSON(value interface{}, withBase64 bool) interface{} {
+	switch v := value.(type) {
+	case string:
+		if decoded, ok := parseEmbeddedJSON(v, withBase64); ok {
+			return decodeEmbeddedJSON(decoded, withBase64)
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = decodeEmbeddedJSON(item, withBase64)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = decodeEmbeddedJSON(item, withBase64)
+		}
+	}
+	return value
+}
+
+// Parses a string holding a JSON object or array, possibly base64 encoded
+func parseEmbeddedJSON(s string, withBase64 bool) (interface{}, bool) {
+	trimmed := strings.TrimSpace(s)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		if decoded, err := parseJSON([]byte(trimmed)); err == nil {
+			return decoded, true
+		}
+		return nil, false
+	}
+	if !withBase64 || trimmed == "" {
+		return nil, false
+	}
+
+	for _, encoding := range base64Encodings {
+		data, err := encoding.DecodeString(trimmed)
+		if err != nil {
+			continue
+		}
+		// Only JSON objects and arrays, so that short strings are not mistaken for encoded numbers
+		if content := strings.TrimSpace(string(data)); strings.HasPrefix(content, "{") || strings.HasPrefix(content, "[") {
+			if decoded, err := parseJSON([]byte(content)); err == nil {
+				return decoded, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Converts a normalized path returned by the JSONPath library into path segments
+func locatedSegments(path spec.NormalizedPath) []pathSegment {
+	segments := make([]pathSegment, 0, len(path))
+	for _, selector := range path {
+		switch s := selector.(type) {
+		case spec.Name:
+			segments = append(segments, pathSegment{key: string(s)})
+		case spec.Index:
+			segments = append(segments, pathSegment{index: int(s)})
+		}
+	}
+	return segments
+}
+
+// Returns the value at a path and reports whether it exists
+func documentValue(data interface{}, segments []pathSegment) (interface{}, bool) {
+	for _, segment := range segments {
+		var ok bool
+		if data, ok = documentChild(data, segment); !ok {
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// Replaces the existing value at a path, returning the new document
+func replaceDocumentValue(data interface{}, segments []pathSegment, value interface{}) interface{} {
+	if len(segments) == 0 {
+		return value
+	}
+
+	parent, ok := documentValue(data, segments[:len(segments)-1])
+	if !ok {
+		return data
+	}
+	last := segments[len(segments)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[last.key] = value
+	case []interface{}:
+		if last.index < len(p) {
+			p[last.index] = value
+		}
+	}
+	return data
+}
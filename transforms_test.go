@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApplyTransforms(t *testing.T) {
+	testCases := []struct {
+		name       string
+		data       string
+		transforms []Transform
+		want       string
+		errorMsg   string
+	}{
+		{
+			name:       "sort by field",
+			data:       `{"users": [{"id": 3}, {"id": 1}, {"name": "none"}, {"id": 2}]}`,
+			transforms: []Transform{{Op: transformSort, Path: "$.users", Field: "id"}},
+			want:       `{"users": [{"name": "none"}, {"id": 1}, {"id": 2}, {"id": 3}]}`,
+		},
+		{
+			name:       "sort by nested field",
+			data:       `[{"meta": {"name": "b"}}, {"meta": {"name": "a"}}]`,
+			transforms: []Transform{{Op: transformSort, Field: "meta.name"}},
+			want:       `[{"meta": {"name": "a"}}, {"meta": {"name": "b"}}]`,
+		},
+		{
+			name:       "sort values of mixed types",
+			data:       `{"tags": ["b", 2, null, "a", true, 1]}`,
+			transforms: []Transform{{Op: transformSort, Path: "$.tags"}},
+			want:       `{"tags": [null, true, 1, 2, "a", "b"]}`,
+		},
+		{
+			name:       "sort every selected array",
+			data:       `{"groups": [{"members": [2, 1]}, {"members": [4, 3]}]}`,
+			transforms: []Transform{{Op: transformSort, Path: "$.groups[*].members"}},
+			want:       `{"groups": [{"members": [1, 2]}, {"members": [3, 4]}]}`,
+		},
+		{
+			name:       "drop nulls",
+			data:       `{"a": null, "b": {"c": null, "d": 1}, "e": [null, {"f": null}]}`,
+			transforms: []Transform{{Op: transformDropNulls}},
+			want:       `{"b": {"d": 1}, "e": [null, {}]}`,
+		},
+		{
+			name:       "unwrap envelope",
+			data:       `{"data": {"items": [1]}, "meta": {"page": 1}}`,
+			transforms: []Transform{{Op: transformUnwrap, Field: "data"}},
+			want:       `{"items": [1]}`,
+		},
+		{
+			name:       "unwrap leaves documents without envelope",
+			data:       `{"items": [1]}`,
+			transforms: []Transform{{Op: transformUnwrap, Field: "data"}},
+			want:       `{"items": [1]}`,
+		},
+		{
+			name:       "map to array with key field",
+			data:       `{"regions": {"us": {"url": "u"}, "eu": {"url": "e"}}}`,
+			transforms: []Transform{{Op: transformMapToArray, Path: "$.regions", Field: "name"}},
+			want:       `{"regions": [{"name": "eu", "url": "e"}, {"name": "us", "url": "u"}]}`,
+		},
+		{
+			name:       "map to array of values",
+			data:       `{"limits": {"b": 2, "a": 1}}`,
+			transforms: []Transform{{Op: transformMapToArray, Path: "$.limits"}},
+			want:       `{"limits": [1, 2]}`,
+		},
+		{
+			name:       "map to array leaves arrays",
+			data:       `{"regions": [{"name": "eu"}]}`,
+			transforms: []Transform{{Op: transformMapToArray, Path: "$.regions", Field: "name"}},
+			want:       `{"regions": [{"name": "eu"}]}`,
+		},
+		{
+			name:       "lowercase selected strings",
+			data:       `{"users": [{"email": "A@X.COM", "name": "Ann"}], "region": "EU"}`,
+			transforms: []Transform{{Op: transformLowercase, Path: "$..email"}},
+			want:       `{"users": [{"email": "a@x.com", "name": "Ann"}], "region": "EU"}`,
+		},
+		{
+			name: "transforms run in order",
+			data: `{"data": {"users": {"b": {"email": "B@X"}, "a": {"email": null}}}}`,
+			transforms: []Transform{
+				{Op: transformUnwrap, Field: "data"},
+				{Op: transformMapToArray, Path: "$.users", Field: "id"},
+				{Op: transformDropNulls},
+				{Op: transformLowercase, Path: "$.users[*].email"},
+			},
+			want: `{"users": [{"id": "a"}, {"id": "b", "email": "b@x"}]}`,
+		},
+		{
+			name:       "decode embedded JSON everywhere",
+			data:       `{"settings": "{\"a\": 1, \"nested\": \"[1, 2]\"}", "items": [" {\"b\": true}"], "name": "{not json", "count": "30"}`,
+			transforms: []Transform{{Op: transformDecodeJSON}},
+			want:       `{"settings": {"a": 1, "nested": [1, 2]}, "items": [{"b": true}], "name": "{not json", "count": "30"}`,
+		},
+		{
+			name:       "decode selected strings only",
+			data:       `{"settings": "{\"a\": 1}", "raw": "{\"a\": 1}"}`,
+			transforms: []Transform{{Op: transformDecodeJSON, Path: "$.settings"}},
+			want:       `{"settings": {"a": 1}, "raw": "{\"a\": 1}"}`,
+		},
+		{
+			name:       "decode base64 encoded JSON",
+			data:       `{"std": "eyJhIjogMX0=", "url": "eyJ1cmwiOiAiaHR0cHM6Ly9leGFtcGxlLmNvbS8_cT0xIn0", "word": "abcd"}`,
+			transforms: []Transform{{Op: transformDecodeJSON, Base64: true}},
+			want:       `{"std": {"a": 1}, "url": {"url": "https://example.com/?q=1"}, "word": "abcd"}`,
+		},
+		{
+			name:       "base64 is opt-in",
+			data:       `{"std": "eyJhIjogMX0="}`,
+			transforms: []Transform{{Op: transformDecodeJSON}},
+			want:       `{"std": "eyJhIjogMX0="}`,
+		},
+		{
+			name:       "key field conflict",
+			data:       `{"regions": {"eu": {"name": "Europe"}}}`,
+			transforms: []Transform{{Op: transformMapToArray, Path: "$.regions", Field: "name"}},
+			errorMsg:   "transform 1 (mapToArray): $['regions']: value of eu already has a field name",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := parseJSON([]byte(tc.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			original := copyJSONValue(data)
+
+			got, err := applyTransforms(data, tc.transforms)
+			if tc.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tc.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			want, _ := parseJSON([]byte(tc.want))
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected %v, got %v", want, got)
+			}
+			if !reflect.DeepEqual(data, original) {
+				t.Errorf("Input was modified: %v", data)
+			}
+		})
+	}
+}
+
+func TestValidateTransform(t *testing.T) {
+	testCases := []struct {
+		name      string
+		transform Transform
+		wantError bool
+	}{
+		{name: "sort", transform: Transform{Op: transformSort, Path: "$.items", Field: "id"}},
+		{name: "drop nulls everywhere", transform: Transform{Op: transformDropNulls}},
+		{name: "missing op", transform: Transform{Path: "$.items"}, wantError: true},
+		{name: "unknown op", transform: Transform{Op: "reverse"}, wantError: true},
+		{name: "invalid path", transform: Transform{Op: transformSort, Path: "$[bad"}, wantError: true},
+		{name: "unwrap without field", transform: Transform{Op: transformUnwrap}, wantError: true},
+		{name: "invalid field", transform: Transform{Op: transformSort, Field: "a..b"}, wantError: true},
+		{name: "field not used", transform: Transform{Op: transformLowercase, Field: "email"}, wantError: true},
+		{name: "decode base64", transform: Transform{Op: transformDecodeJSON, Path: "$.payload", Base64: true}},
+		{name: "base64 not used", transform: Transform{Op: transformSort, Base64: true}, wantError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.transform.validate()
+			if tc.wantError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tc.wantError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCompareEndpointsWithTransforms(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1" {
+			fmt.Fprint(w, `{"data": {"users": [{"id": 2, "email": "B@X"}, {"id": 1, "email": "a@x", "phone": null}]}}`)
+			return
+		}
+		fmt.Fprint(w, `{"users": [{"id": 1, "email": "a@x"}, {"id": 2, "email": "b@x"}]}`)
+	}))
+	defer server.Close()
+
+	config := &Config{
+		Endpoints: []Endpoint{
+			{Name: "v1", URL: server.URL + "/v1"},
+			{Name: "v2", URL: server.URL + "/v2"},
+		},
+		Settings: Settings{Transforms: []Transform{
+			{Op: transformUnwrap, Field: "data"},
+			{Op: transformSort, Path: "$.users", Field: "id"},
+			{Op: transformDropNulls},
+			{Op: transformLowercase, Path: "$.users[*].email"},
+		}},
+	}
+	setDefaults(config)
+
+	result, err := compareEndpoints(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.unaccepted) != 0 {
+		t.Errorf("Expected no differences, got %v", result.unaccepted)
+	}
+
+	// Verbose mode shows the transformed documents
+	var out bytes.Buffer
+	if err := printDocuments(&out, config.Endpoints[0], config.Endpoints[1], result); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Document A (v1) after extraction and transforms:\n{\n  \"users\"") {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+}
+
+func TestDecodedPaths(t *testing.T) {
+	dataA, _ := parseJSON([]byte(`{"settings": "{\"timeout\": 30, \"retries\": 3}"}`))
+	dataB, _ := parseJSON([]byte(`{"settings": {"timeout": 60, "retries": 3}}`))
+
+	transforms := []Transform{{Op: transformDecodeJSON}}
+	dataA, dataB, err := transformDocuments(dataA, dataB, transforms)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Differences are reported inside the decoded content
+	diffs := diffJSON(dataA, dataB, nil)
+	if len(diffs) != 1 || diffs[0].path != "settings.timeout" {
+		t.Errorf("Expected a difference at settings.timeout, got %v", diffs)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// Default interval between watch comparisons
+const defaultWatchInterval = 5 * time.Minute
+
+// Suffix of the default watch state file
+const watchStateSuffix = ".watch-state.json"
+
+// Represents the result of a watch comparison, persisted between runs
+type watchState struct {
+	CheckedAt   time.Time `json:"checkedAt"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	Differences []string  `json:"differences,omitempty"`
+}
+
+// Represents a change between two watch states
+type watchEvent struct {
+	kind    string
+	details []string
+}
+
+// Runs the watch subcommand and returns the exit code
+func runWatch(args []string) int {
+	fs := newCommandFlagSet("watch", "config.yaml [--interval 5m] [--state file]")
+	profile := profileFlag(fs)
+	interval := fs.Duration("interval", defaultWatchInterval, "interval between comparisons")
+	statePath := fs.String("state", "", "file to keep the last result in (default: config file + "+watchStateSuffix+")")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return flagErrorCode(err)
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return 2
+	}
+	if *interval <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: interval must be positive")
+		return 2
+	}
+
+	config, err := LoadConfig(positional[0], *profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config file: %v\n", err)
+		return 2
+	}
+	if config.readsStdin() {
+		fmt.Fprintln(os.Stderr, "Error: standard input can only be read once and cannot be watched")
+		return 2
+	}
+
+	if *statePath == "" {
+		*statePath = positional[0] + watchStateSuffix
+	}
+
+	// Resume from the state of a previous run
+	previous, err := loadWatchState(*statePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading watch state: %v\n", err)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		current := checkWatchState(config)
+		for _, event := range diffWatchStates(previous, current) {
+			printWatchEvent(current, event)
+		}
+
+		if err := saveWatchState(*statePath, current); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving watch state: %v\n", err)
+		}
+		previous = current
+
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-ticker.C:
+		}
+	}
+}
+
+// Compares the endpoints once and converts the result into a watch state
+func checkWatchState(config *Config) *watchState {
+	state := &watchState{CheckedAt: time.Now().UTC()}
+
+	result, err := compareEndpoints(config)
+	if err != nil {
+		state.Status = statusError
+		state.Error = secrets.redact(err.Error())
+		return state
+	}
+
+	// Only differences that fail the comparison and schema violations count as drift
+	for _, diff := range result.unaccepted {
+		state.Differences = append(state.Differences, formatDiff(diff))
+	}
+	for _, m := range result.expired {
+		state.Differences = append(state.Differences, formatDiff(m.diff))
+	}
+	for _, v := range result.violations {
+		state.Differences = append(state.Differences, formatViolation(v))
+	}
+	sort.Strings(state.Differences)
+
+	switch result.exitCode() {
+	case exitSchemaViolation:
+		state.Status = statusInvalid
+	case 1:
+		state.Status = statusDifferent
+	default:
+		state.Status = statusIdentical
+	}
+	return state
+}
+
+// Returns the events describing the change from the previous to the current state
+func diffWatchStates(previous, current *watchState) []watchEvent {
+	// Report the initial state when there is no previous run
+	if previous == nil {
+		if current.Status == statusError {
+			return []watchEvent{{"endpoint failing", []string{current.Error}}}
+		}
+		return []watchEvent{{"initial state: " + current.Status, current.Differences}}
+	}
+
+	var events []watchEvent
+
+	// Endpoint failures
+	if current.Status == statusError {
+		if previous.Status != statusError || previous.Error != current.Error {
+			events = append(events, watchEvent{"endpoint failing", []string{current.Error}})
+		}
+		return events
+	}
+	if previous.Status == statusError {
+		events = append(events, watchEvent{"endpoint recovered", nil})
+	}
+
+	// Differences appearing and disappearing
+	added, removed := diffStringSets(previous.Differences, current.Differences)
+	if previous.Status == statusError {
+		// Differences before the failure are unknown, so report the current set
+		added, removed = current.Differences, nil
+	}
+	if len(added) > 0 {
+		events = append(events, watchEvent{"drift detected", added})
+	}
+	if len(removed) > 0 {
+		events = append(events, watchEvent{"drift resolved", removed})
+	}
+
+	return events
+}
+
+// Returns the elements only in b (added) and only in a (removed)
+func diffStringSets(a, b []string) ([]string, []string) {
+	inA := make(map[string]bool, len(a))
+	for _, s := range a {
+		inA[s] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, s := range b {
+		inB[s] = true
+	}
+
+	var added, removed []string
+	for _, s := range b {
+		if !inA[s] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range a {
+		if !inB[s] {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
+
+// Prints a watch event with the exit code the one-shot comparison would return
+func printWatchEvent(state *watchState, event watchEvent) {
+	fmt.Printf("[%s] %s (status %d)\n", state.CheckedAt.Format(time.RFC3339), event.kind, watchExitCode(state))
+	for _, detail := range event.details {
+		fmt.Println(detail)
+	}
+}
+
+// Returns the exit code corresponding to a watch state
+func watchExitCode(state *watchState) int {
+	switch state.Status {
+	case statusInvalid:
+		return exitSchemaViolation
+	case statusDifferent:
+		return 1
+	case statusError:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Loads the watch state, returning nil if none has been saved yet
+func loadWatchState(path string) (*watchState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var state watchState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Saves the watch state
+func saveWatchState(path string, state *watchState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiffWatchStates(t *testing.T) {
+	identical := &watchState{Status: statusIdentical}
+	driftA := &watchState{Status: statusDifferent, Differences: []string{"a"}}
+	driftAB := &watchState{Status: statusDifferent, Differences: []string{"a", "b"}}
+	failing := &watchState{Status: statusError, Error: "connection refused"}
+
+	testCases := []struct {
+		name     string
+		previous *watchState
+		current  *watchState
+		want     []string
+	}{
+		{"first run", nil, identical, []string{"initial state: identical"}},
+		{"unchanged identical", identical, identical, nil},
+		{"unchanged drift", driftA, driftA, nil},
+		{"drift appears", identical, driftA, []string{"drift detected"}},
+		{"drift grows", driftA, driftAB, []string{"drift detected"}},
+		{"drift shrinks", driftAB, driftA, []string{"drift resolved"}},
+		{"drift disappears", driftA, identical, []string{"drift resolved"}},
+		{"endpoint starts failing", driftA, failing, []string{"endpoint failing"}},
+		{"endpoint keeps failing", failing, failing, nil},
+		{"endpoint recovers with drift", failing, driftA, []string{"endpoint recovered", "drift detected"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var kinds []string
+			for _, event := range diffWatchStates(tc.previous, tc.current) {
+				kinds = append(kinds, event.kind)
+			}
+			if !reflect.DeepEqual(kinds, tc.want) {
+				t.Errorf("diffWatchStates() = %v, want %v", kinds, tc.want)
+			}
+		})
+	}
+}
+
+func TestWatchStatePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	state, err := loadWatchState(path)
+	if err != nil || state != nil {
+		t.Fatalf("Expected no state before first save, got %v (err: %v)", state, err)
+	}
+
+	saved := &watchState{Status: statusDifferent, Differences: []string{"- Path: x"}}
+	if err := saveWatchState(path, saved); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	state, err = loadWatchState(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(state, saved) {
+		t.Errorf("Loaded state %v, want %v", state, saved)
+	}
+}